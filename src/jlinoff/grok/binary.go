@@ -0,0 +1,103 @@
+// Binary file detection.
+package main
+
+import (
+	"bytes"
+	"unicode/utf8"
+)
+
+// The valid values of --binary:
+//
+//	skip   binary files are not searched
+//	match  binary files are searched, the matched lines are not printed
+//	text   binary files are searched like text files
+var binaryModes = []string{"skip", "match", "text"}
+
+// byteOrderMark is the byte order mark at the start of a Unicode file.
+type byteOrderMark struct {
+	BOM      []byte
+	Encoding string
+}
+
+// The byte order marks. UTF-32LE must come before UTF-16LE because it
+// starts with the same bytes.
+var byteOrderMarks = []byteOrderMark{
+	{BOM: []byte{0xEF, 0xBB, 0xBF}, Encoding: "utf-8"},
+	{BOM: []byte{0x00, 0x00, 0xFE, 0xFF}, Encoding: "utf-32be"},
+	{BOM: []byte{0xFF, 0xFE, 0x00, 0x00}, Encoding: "utf-32le"},
+	{BOM: []byte{0xFE, 0xFF}, Encoding: "utf-16be"},
+	{BOM: []byte{0xFF, 0xFE}, Encoding: "utf-16le"},
+}
+
+// The largest shares of the bytes that are not valid UTF-8 and of the
+// control characters in a text file.
+const (
+	maxInvalidUTF8Share = 0.30
+	maxControlShare     = 0.10
+)
+
+// findByteOrderMark returns the byte order mark at the start of the data
+// or nil if there is none.
+func findByteOrderMark(data []byte) *byteOrderMark {
+	for i := range byteOrderMarks {
+		if bytes.HasPrefix(data, byteOrderMarks[i].BOM) {
+			return &byteOrderMarks[i]
+		}
+	}
+	return nil
+}
+
+// isBinary determines whether a file is binary from the first
+// --binary-size bytes of the head:
+//
+//   - a file that starts with a byte order mark is text
+//   - a file that contains a NUL byte is binary
+//   - a file is binary if more than 30% of the bytes are not valid
+//     UTF-8 or more than 10% of the characters are control characters
+//
+// Everything else is text, including empty files, files without a
+// newline and files in 8-bit encodings like Latin-1 that are mostly
+// ASCII.
+func isBinary(opts cliOptions, head []byte) bool {
+	if len(head) > opts.BinarySize {
+		head = head[:opts.BinarySize]
+	}
+	if findByteOrderMark(head) != nil {
+		return false
+	}
+	if bytes.IndexByte(head, 0) >= 0 {
+		return true
+	}
+
+	invalid := 0 // bytes that are not valid UTF-8
+	control := 0 // control characters
+	for i := 0; i < len(head); {
+		r, n := utf8.DecodeRune(head[i:])
+		switch {
+		case r == utf8.RuneError && n == 1:
+			// The last character may be cut off by the sample
+			// size, it is not counted.
+			if utf8.FullRune(head[i:]) {
+				invalid++
+			}
+		case r < 0x20 || r == 0x7F:
+			if isTextControl(r) == false {
+				control++
+			}
+		}
+		i += n
+	}
+	return float64(invalid) > maxInvalidUTF8Share*float64(len(head)) ||
+		float64(control) > maxControlShare*float64(len(head))
+}
+
+// isTextControl returns true for the control characters that are common
+// in text files: white space, backspace (man pages) and escape (terminal
+// colors in logs).
+func isTextControl(r rune) bool {
+	switch r {
+	case '\t', '\n', '\v', '\f', '\r', '\b', 0x1B:
+		return true
+	}
+	return false
+}
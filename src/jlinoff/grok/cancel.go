@@ -0,0 +1,166 @@
+// Cancellation.
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// The reasons why a search was stopped early.
+// Only errLimitReached is expected, the others mean that the results are
+// partial.
+var (
+	errBrokenPipe   = errors.New("broken pipe")
+	errFileTimeout  = errors.New("file timeout")
+	errInterrupted  = errors.New("interrupted")
+	errLimitReached = errors.New("match limit reached")
+	errTimeout      = errors.New("timeout")
+)
+
+// Stops the search with a reason. It is set by newSearchContext.
+var cancelSearch context.CancelCauseFunc = func(error) {}
+
+// Writes to stdout and stops the search if the reader went away.
+var stdout io.Writer = stdoutWriter{}
+
+// newSearchContext creates the context for the search. It is cancelled by
+// SIGINT, by a broken stdout pipe, when --timeout expires or when
+// cancelSearch is called.
+func newSearchContext(opts cliOptions) context.Context {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	cancelSearch = cancel
+	if opts.Timeout > 0 {
+		var stop context.CancelFunc
+		ctx, stop = context.WithTimeoutCause(ctx, opts.Timeout, errTimeout)
+		cancelSearch = func(cause error) {
+			cancel(cause)
+			stop()
+		}
+	}
+
+	// The first SIGINT stops the search gracefully, the default action
+	// is restored so that a second one terminates the program.
+	// SIGPIPE is caught so that writes to a closed pipe return EPIPE
+	// rather than terminating the program.
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGPIPE)
+	go func() {
+		for sig := range sigs {
+			if sig == os.Interrupt {
+				signal.Reset(os.Interrupt)
+				cancel(errInterrupted)
+			}
+		}
+	}()
+	return ctx
+}
+
+// searchStopped returns true if the search was stopped.
+func searchStopped(ctx context.Context) bool {
+	return ctx.Err() != nil
+}
+
+// abandonFile reports why a file was abandoned. Only file timeouts are
+// search errors, the other reasons stop the whole search.
+func abandonFile(ctx context.Context, opts cliOptions, path string, fs *findStats) {
+	cause := context.Cause(ctx)
+	if cause == errFileTimeout {
+		err := fmt.Errorf("no result after %v", opts.FileTimeout)
+		reportSearchError(opts, fs, &searchError{Kind: fileTimeout, Path: path, Err: err})
+	} else {
+		infov2(opts, "search stopped, abandoning file: '%v' - %v", path, cause)
+	}
+}
+
+// partialReason returns the reason why the results are partial or nil if
+// the search was completed or stopped by a match limit.
+func partialReason(ctx context.Context) error {
+	cause := context.Cause(ctx)
+	if cause == nil || cause == errLimitReached {
+		return nil
+	}
+	return cause
+}
+
+// stdoutWriter writes to stdout. If the pipe was closed by the reader,
+// like head, the search is stopped.
+type stdoutWriter struct{}
+
+func (stdoutWriter) Write(p []byte) (int, error) {
+	n, err := os.Stdout.Write(p)
+	if err != nil && errors.Is(err, syscall.EPIPE) {
+		cancelSearch(errBrokenPipe)
+	}
+	return n, err
+}
+
+// openFile opens a file for reading. If the context has a deadline, the
+// open and the reads are done in separate goroutines so that a file that
+// hangs, like a file on a dead NFS server, gives up the worker when the
+// deadline expires. The blocked goroutine is abandoned.
+func openFile(ctx context.Context, path string) (io.ReadCloser, error) {
+	if _, ok := ctx.Deadline(); ok == false {
+		return fileSystem.Open(path)
+	}
+	type result struct {
+		file io.ReadCloser
+		err  error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		file, err := fileSystem.Open(path)
+		ch <- result{file, err}
+	}()
+	select {
+	case <-ctx.Done():
+		go func() {
+			// Close the file if the open ever completes.
+			if r := <-ch; r.err == nil {
+				r.file.Close()
+			}
+		}()
+		return nil, context.Cause(ctx)
+	case r := <-ch:
+		if r.err != nil {
+			return nil, r.err
+		}
+		return ctxReader{ctx: ctx, file: r.file}, nil
+	}
+}
+
+// ctxReader reads from a file until the context is done.
+type ctxReader struct {
+	ctx  context.Context
+	file io.ReadCloser
+}
+
+func (cr ctxReader) Read(p []byte) (int, error) {
+	type result struct {
+		n   int
+		err error
+	}
+	// The buffer cannot be shared with a goroutine that may be
+	// abandoned.
+	buf := make([]byte, len(p))
+	ch := make(chan result, 1)
+	go func() {
+		n, err := cr.file.Read(buf)
+		ch <- result{n, err}
+	}()
+	select {
+	case <-cr.ctx.Done():
+		return 0, context.Cause(cr.ctx)
+	case r := <-ch:
+		copy(p, buf[:r.n])
+		return r.n, r.err
+	}
+}
+
+func (cr ctxReader) Close() error {
+	return cr.file.Close()
+}
@@ -0,0 +1,219 @@
+// Colorizing.
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// colorScheme holds the ANSI SGR codes, like "38;5;245", for each part
+// of the output. An empty code disables the color for that part.
+type colorScheme struct {
+	Context string   // context lines
+	Line    string   // line numbers
+	Match   []string // matches, one color per pattern
+	Path    string   // file names
+	Sep     string   // separators
+}
+
+// colorSpan is the location of a pattern match in a line.
+type colorSpan struct {
+	Start   int
+	End     int
+	Pattern int
+}
+
+// The host name used in file hyperlinks.
+var hostname, _ = os.Hostname()
+
+// Valid SGR code.
+var sgrRe = regexp.MustCompile(`^[0-9;]*$`)
+
+// defaultColorScheme returns the built-in colors.
+func defaultColorScheme() colorScheme {
+	return colorScheme{
+		Context: "38;5;245",
+		Line:    "38;5;245",
+		Match:   []string{"31;1", "32;1", "34;1", "35;1", "36;1", "33;1"},
+		Path:    "1",
+		Sep:     "38;5;245",
+	}
+}
+
+// loadColorScheme returns the built-in colors updated by the GROK_COLORS
+// environment variable.
+func loadColorScheme() (colorScheme, error) {
+	cs := defaultColorScheme()
+	if spec, ok := os.LookupEnv("GROK_COLORS"); ok {
+		if err := cs.update(spec); err != nil {
+			return cs, optionErrorf("invalid GROK_COLORS '%v': %v", spec, err)
+		}
+	}
+	return cs, nil
+}
+
+// update updates the colors from a specification of colon separated
+// NAME=CODE entries. The match entry accepts a comma separated list of
+// codes that are assigned to the accept patterns in order, for example:
+//
+//	path=35:line=32:sep=36:context=2:match=31;1,32;1,34;1
+func (cs *colorScheme) update(spec string) error {
+	for _, entry := range strings.Split(spec, ":") {
+		if len(entry) == 0 {
+			continue
+		}
+		flds := strings.SplitN(entry, "=", 2)
+		if len(flds) != 2 {
+			return fmt.Errorf("missing '=' in '%v'", entry)
+		}
+		name, code := flds[0], flds[1]
+		codes := strings.Split(code, ",")
+		for _, c := range codes {
+			if sgrRe.MatchString(c) == false {
+				return fmt.Errorf("invalid color code '%v' for %v", c, name)
+			}
+		}
+		switch name {
+		case "context":
+			cs.Context = code
+		case "line":
+			cs.Line = code
+		case "match":
+			cs.Match = codes
+		case "path":
+			cs.Path = code
+		case "sep":
+			cs.Sep = code
+		default:
+			return fmt.Errorf("unknown name '%v'", name)
+		}
+	}
+	return nil
+}
+
+// useColor decides whether the output is colorized. Explicit always and
+// never modes win. In auto mode a non-empty NO_COLOR disables colors, a
+// CLICOLOR_FORCE other than 0 forces them and otherwise colors are only
+// used if stdout is a terminal.
+func useColor(mode string) bool {
+	switch mode {
+	case "always":
+		return true
+	case "never":
+		return false
+	}
+	if len(os.Getenv("NO_COLOR")) > 0 {
+		return false
+	}
+	if f := os.Getenv("CLICOLOR_FORCE"); len(f) > 0 && f != "0" {
+		return true
+	}
+	return isTerminal(os.Stdout)
+}
+
+// isTerminal reports whether the file is a terminal (character device).
+func isTerminal(f *os.File) bool {
+	stat, err := f.Stat()
+	return err == nil && stat.Mode()&os.ModeCharDevice != 0
+}
+
+// paintPath paints a file name in the --path-style. If --hyperlink was
+// specified, it is also wrapped in an OSC 8 hyperlink to the file so
+// that it can be clicked in terminals that support them. The files in a
+// --zip archive link to the archive because they are not on the disk.
+func paintPath(opts cliOptions, path string) string {
+	text := paint(opts.Colors.Path, displayPath(opts, path))
+	if opts.Hyperlink == false || opts.Colorize == false {
+		return text
+	}
+	if len(opts.Zip) > 0 {
+		path = opts.Zip
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return text
+	}
+	u := url.URL{Scheme: "file", Host: hostname, Path: filepath.ToSlash(abs)}
+	return "\033]8;;" + u.String() + "\033\\" + text + "\033]8;;\033\\"
+}
+
+// paint wraps the text in the ANSI escape sequences for the code.
+func paint(code string, text string) string {
+	if len(code) == 0 || len(text) == 0 {
+		return text
+	}
+	return "\033[" + code + "m" + text + "\033[0m"
+}
+
+// colorizeLine colorizes the matches in a matched line.
+func colorizeLine(opts cliOptions, line string) string {
+	return highlight(opts, line, "")
+}
+
+// colorizeContextLine colorizes a context line, matches in it are still
+// highlighted.
+func colorizeContextLine(opts cliOptions, line string) string {
+	return highlight(opts, line, opts.Colors.Context)
+}
+
+// highlight paints the matches of the highlight patterns in the line
+// using the color of the pattern that matched. The rest of the line is
+// painted with the base color.
+func highlight(opts cliOptions, line string, base string) string {
+	spans := matchSpans(highlightPatterns(opts), line)
+	if len(spans) == 0 || len(opts.Colors.Match) == 0 {
+		return paint(base, line)
+	}
+	var sb strings.Builder
+	prev := 0
+	for _, s := range spans {
+		sb.WriteString(paint(base, line[prev:s.Start]))
+		sb.WriteString(paint(opts.Colors.Match[s.Pattern%len(opts.Colors.Match)], line[s.Start:s.End]))
+		prev = s.End
+	}
+	sb.WriteString(paint(base, line[prev:]))
+	return sb.String()
+}
+
+// highlightPatterns returns the patterns that are highlighted in the
+// order that they are assigned colors.
+func highlightPatterns(opts cliOptions) (ps []*regexp.Regexp) {
+	ps = append(ps, opts.AcceptOrPatterns...)
+	ps = append(ps, opts.AcceptAndPatterns...)
+	ps = append(ps, opts.ShowPatterns...)
+	return
+}
+
+// matchSpans finds the matches of all of the patterns in the original
+// line and resolves the overlaps. Earlier matches win and, when two
+// matches start at the same place, the longest one wins. Empty matches
+// are ignored.
+func matchSpans(ps []*regexp.Regexp, line string) (spans []colorSpan) {
+	all := []colorSpan{}
+	for i, p := range ps {
+		for _, loc := range p.FindAllStringIndex(line, -1) {
+			if loc[1] > loc[0] {
+				all = append(all, colorSpan{Start: loc[0], End: loc[1], Pattern: i})
+			}
+		}
+	}
+	sort.SliceStable(all, func(i, j int) bool {
+		if all[i].Start != all[j].Start {
+			return all[i].Start < all[j].Start
+		}
+		return all[i].End > all[j].End
+	})
+	end := 0
+	for _, s := range all {
+		if s.Start >= end {
+			spans = append(spans, s)
+			end = s.End
+		}
+	}
+	return
+}
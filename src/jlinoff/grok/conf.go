@@ -0,0 +1,318 @@
+// Conf file parsing.
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// confOriginFile returns the conf file of a file:line origin. It returns
+// false for the other origins like the command line and GROK_OPTS.
+func confOriginFile(origin string) (string, bool) {
+	k := strings.LastIndex(origin, ":")
+	if k <= 0 {
+		return "", false
+	}
+	if _, err := strconv.Atoi(origin[k+1:]); err != nil {
+		return "", false
+	}
+	return origin[:k], true
+}
+
+// readConfFile reads the arguments in a conf file. Each line holds any
+// number of arguments that are split like the shell does, see
+// tokenizeConfLine. The "include PATH" directive inserts the arguments
+// of another conf file, a relative path is relative to the directory of
+// the conf file that includes it.
+// A "[profile NAME]" line starts a profile section, the arguments that
+// follow it up to the next section are registered as a profile instead
+// of being returned, see profile.go.
+// The confMap maps the canonical paths of the conf files that were
+// already read to their names so that recursive references are caught.
+// The ref is the file:line location of the include directive or empty
+// for a conf file on the command line.
+// The file:line origin of each argument is returned in origins.
+// The conf files are read from fsys, which is the disk except for the
+// directory conf files that are found by the walk.
+func readConfFile(fsys searchFS, conf string, ref string, confMap map[string]string) (newargs []string, origins []string, err error) {
+	path, err := fsys.CanonicalPath(conf)
+	if err != nil {
+		return nil, nil, optionErrorf("conf file read failed %v: %v", conf, err)
+	}
+	if _, found := confMap[path]; found {
+		// It was found! This could be an infinite recursive descent.
+		if len(ref) > 0 {
+			return nil, nil, optionErrorf("%v: nested reference to file '%v'", ref, conf)
+		}
+		return nil, nil, optionErrorf("nested reference to file '%v' found in conf file '%v'", conf, confMap[path])
+	}
+	confMap[path] = conf
+	ifp, err := fsys.Open(conf)
+	if err != nil {
+		return nil, nil, optionErrorf("conf file read failed %v: %v", conf, err)
+	}
+	defer ifp.Close()
+
+	var profile *confProfile // the current profile section
+	s := bufio.NewScanner(ifp)
+	for lineno := 1; s.Scan(); lineno++ {
+		origin := fmt.Sprintf("%v:%v", conf, lineno)
+		if m := profileHeaderRe.FindStringSubmatch(s.Text()); m != nil {
+			profile = &confProfile{Name: m[1], Origin: origin}
+			confProfilesMutex.Lock()
+			confProfiles[profile.Name] = profile
+			confProfilesMutex.Unlock()
+			continue
+		}
+		toks, err := tokenizeConfLine(s.Text())
+		if err != nil {
+			return nil, nil, optionErrorf("%v: %v", origin, err)
+		}
+		if len(toks) > 0 && toks[0] == "extends" {
+			if profile == nil {
+				return nil, nil, optionErrorf("%v: extends is only allowed in a profile section", origin)
+			}
+			profile.Extends = append(profile.Extends, toks[1:]...)
+			continue
+		}
+		if len(toks) > 0 && toks[0] == "include" {
+			if len(toks) != 2 {
+				return nil, nil, optionErrorf("%v: include expects one path, found %v", origin, len(toks)-1)
+			}
+			inc := toks[1]
+			if filepath.IsAbs(inc) == false {
+				inc = filepath.Join(filepath.Dir(conf), inc)
+			}
+			incargs, incorigins, err := readConfFile(fsys, inc, origin, confMap)
+			if err != nil {
+				return nil, nil, err
+			}
+			if profile != nil {
+				profile.Args = append(profile.Args, incargs...)
+				profile.Origins = append(profile.Origins, incorigins...)
+			} else {
+				newargs = append(newargs, incargs...)
+				origins = append(origins, incorigins...)
+			}
+			continue
+		}
+		for _, tok := range toks {
+			if profile != nil {
+				profile.Args = append(profile.Args, tok)
+				profile.Origins = append(profile.Origins, origin)
+			} else {
+				newargs = append(newargs, tok)
+				origins = append(origins, origin)
+			}
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, nil, optionErrorf("conf file read failed: %v: %v", conf, err)
+	}
+	return
+}
+
+// loadConfigArgs returns the arguments from the implicit config sources
+// in order of increasing precedence:
+//
+//  1. the user config file, $XDG_CONFIG_HOME/grok/config
+//  2. the nearest .grokrc in the current directory or its parents
+//  3. the GROK_OPTS environment variable
+//
+// They are all skipped if --no-config is in the command line arguments.
+// The origin of each argument is returned in origins and the sources
+// that were used are described in trace.
+func loadConfigArgs(cliArgs []string) (args []string, origins []string, trace []string, err error) {
+	for _, arg := range cliArgs {
+		if arg == "--no-config" {
+			trace = append(trace, "skipped by --no-config")
+			return
+		}
+	}
+
+	for _, conf := range []string{userConfigFile(), projectConfigFile()} {
+		if len(conf) == 0 {
+			continue
+		}
+		cargs, corigins, err := readConfFile(osFS{}, conf, "", map[string]string{})
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		args = append(args, cargs...)
+		origins = append(origins, corigins...)
+		trace = append(trace, conf)
+	}
+
+	if env, ok := os.LookupEnv("GROK_OPTS"); ok {
+		toks, err := tokenizeConfLine(env)
+		if err != nil {
+			return nil, nil, nil, optionErrorf("GROK_OPTS: %v", err)
+		}
+		for _, tok := range toks {
+			args = append(args, tok)
+			origins = append(origins, "GROK_OPTS")
+		}
+		trace = append(trace, "GROK_OPTS")
+	}
+	return
+}
+
+// userConfigFile returns the path to the user config file or an empty
+// string if it does not exist. If XDG_CONFIG_HOME is not set, ~/.config
+// is used.
+func userConfigFile() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if len(dir) == 0 {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	conf := filepath.Join(dir, "grok", "config")
+	if stat, err := os.Stat(conf); err != nil || stat.IsDir() {
+		return ""
+	}
+	return conf
+}
+
+// projectConfigFile returns the path to the nearest .grokrc file in the
+// current directory or one of its parents or an empty string if there is
+// none.
+func projectConfigFile() string {
+	dir, err := os.Getwd()
+	if err != nil {
+		return ""
+	}
+	for {
+		conf := filepath.Join(dir, ".grokrc")
+		if stat, err := os.Stat(conf); err == nil && stat.IsDir() == false {
+			return conf
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// tokenizeConfLine splits a conf file line into arguments like the
+// shell does:
+//
+//   - Arguments are separated by white space.
+//   - Single quotes preserve everything up to the closing quote.
+//   - Double quotes preserve white space but $VAR and ${VAR} are
+//     expanded and a backslash escapes \, ", $ and `.
+//   - Outside of quotes $VAR and ${VAR} are expanded and a backslash
+//     escapes white space, quotes, \, $ and #.
+//   - A # at the start of an argument starts a comment.
+//
+// Unlike the shell, a backslash that does not escape anything is kept
+// so that regular expressions like \bfoo\b do not have to be quoted.
+// Undefined variables expand to an empty string.
+func tokenizeConfLine(line string) (toks []string, err error) {
+	var tok strings.Builder
+	inTok := false // distinguishes '' from no argument
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\r':
+			if inTok {
+				toks = append(toks, tok.String())
+				tok.Reset()
+				inTok = false
+			}
+		case c == '#' && inTok == false:
+			return
+		case c == '\'':
+			k := strings.IndexByte(line[i+1:], '\'')
+			if k < 0 {
+				return nil, fmt.Errorf("unterminated single quote")
+			}
+			tok.WriteString(line[i+1 : i+1+k])
+			i += k + 1
+			inTok = true
+		case c == '"':
+			i++
+			for ; i < len(line) && line[i] != '"'; i++ {
+				switch {
+				case line[i] == '\\' && i+1 < len(line) && strings.IndexByte("\\\"$`", line[i+1]) >= 0:
+					i++
+					tok.WriteByte(line[i])
+				case line[i] == '$':
+					n, err := expandConfVar(line[i:], &tok)
+					if err != nil {
+						return nil, err
+					}
+					i += n - 1
+				default:
+					tok.WriteByte(line[i])
+				}
+			}
+			if i >= len(line) {
+				return nil, fmt.Errorf("unterminated double quote")
+			}
+			inTok = true
+		case c == '\\' && i+1 < len(line) && strings.IndexByte(" \t'\"\\$#", line[i+1]) >= 0:
+			i++
+			tok.WriteByte(line[i])
+			inTok = true
+		case c == '$':
+			n, err := expandConfVar(line[i:], &tok)
+			if err != nil {
+				return nil, err
+			}
+			i += n - 1
+			inTok = true
+		default:
+			tok.WriteByte(c)
+			inTok = true
+		}
+	}
+	if inTok {
+		toks = append(toks, tok.String())
+	}
+	return
+}
+
+// expandConfVar expands the $VAR or ${VAR} reference at the start of s
+// and returns the number of bytes that it used. A $ that is not
+// followed by a variable name is kept.
+func expandConfVar(s string, tok *strings.Builder) (int, error) {
+	if strings.HasPrefix(s, "${") {
+		k := strings.IndexByte(s, '}')
+		if k < 0 {
+			return 0, fmt.Errorf("missing '}' in '%v'", s)
+		}
+		name := s[2:k]
+		if len(name) == 0 || len(confVarName(name)) != len(name) {
+			return 0, fmt.Errorf("invalid variable name in '%v'", s[:k+1])
+		}
+		tok.WriteString(os.Getenv(name))
+		return k + 1, nil
+	}
+	name := confVarName(s[1:])
+	if len(name) == 0 {
+		tok.WriteByte('$')
+		return 1, nil
+	}
+	tok.WriteString(os.Getenv(name))
+	return len(name) + 1, nil
+}
+
+// confVarName returns the longest variable name at the start of s.
+func confVarName(s string) string {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (i > 0 && c >= '0' && c <= '9') {
+			continue
+		}
+		return s[:i]
+	}
+	return s
+}
@@ -0,0 +1,97 @@
+// Date/time specifications and file timestamps.
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The relative date/time specifications: an integer with an optional
+// unit suffix that is subtracted from the current time.
+var relativeDatetimes = []cliDatetime{
+	{Re: regexp.MustCompile("^([0-9]+)$"), Scale: time.Second},
+	{Re: regexp.MustCompile("^([0-9]+)[s]$"), Scale: time.Second},
+	{Re: regexp.MustCompile("^([0-9]+)[m]$"), Scale: time.Minute},
+	{Re: regexp.MustCompile("^([0-9]+)[h]$"), Scale: time.Hour},
+	{Re: regexp.MustCompile("^([0-9]+)[d]$"), Scale: time.Hour * time.Duration(24)},
+	{Re: regexp.MustCompile("^([0-9]+)[w]$"), Scale: time.Hour * time.Duration(24) * time.Duration(7)},
+}
+
+// The absolute date/time layouts, ISO 8601 and RFC 3339. A 'T' or a
+// space separates the date and the time. Fractional seconds are always
+// accepted after the seconds. Values without a time zone are local.
+var absoluteDatetimeLayouts = []string{
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// The time zone suffixes of the absolute layouts: Z, +07:00, +0700 and
+// +07.
+var timezoneLayouts = []string{"Z07:00", "Z0700", "Z07"}
+
+// The valid values of --time-field.
+var timeFields = []string{"mtime", "atime", "ctime"}
+
+// parseDatetime parses a date/time specification. It is a relative
+// specification like 5d, a keyword (now, today or yesterday) or an
+// absolute date/time like 2017-03-01, 2017-03-01T13:00 or
+// 2017-03-01T13:00:00Z.
+func parseDatetime(arg string, now time.Time) (time.Time, error) {
+	for _, rec := range relativeDatetimes {
+		result := rec.Re.FindStringSubmatch(arg)
+		if len(result) > 1 {
+			val, err := strconv.Atoi(result[1])
+			if err != nil {
+				return now, err
+			}
+			td := time.Duration(val) * rec.Scale
+			return now.Add(-td), nil
+		}
+	}
+
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	switch strings.ToLower(arg) {
+	case "now":
+		return now, nil
+	case "today":
+		return midnight, nil
+	case "yesterday":
+		return midnight.AddDate(0, 0, -1), nil
+	}
+
+	for _, layout := range absoluteDatetimeLayouts {
+		if t, err := time.ParseInLocation(layout, arg, now.Location()); err == nil {
+			return t, nil
+		}
+		for _, tz := range timezoneLayouts {
+			if t, err := time.Parse(layout+tz, arg); err == nil {
+				return t, nil
+			}
+		}
+	}
+	return now, fmt.Errorf("expected values like 5d, today or 2017-03-01T13:00:00Z")
+}
+
+// fileTime returns the timestamp of a file that is selected by
+// --time-field. The access and change times are not available on all
+// platforms, the modification time is used instead.
+func fileTime(opts cliOptions, stat os.FileInfo) time.Time {
+	switch opts.TimeField {
+	case "atime":
+		if t, ok := accessTime(stat); ok {
+			return t
+		}
+	case "ctime":
+		if t, ok := changeTime(stat); ok {
+			return t
+		}
+	}
+	return stat.ModTime()
+}
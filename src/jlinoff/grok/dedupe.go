@@ -0,0 +1,52 @@
+// Files and directories that are reached more than once.
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// fileKey identifies a file independently of the path that was used to
+// reach it. It is the device and inode where they are available and the
+// canonical path otherwise.
+type fileKey struct {
+	Dev  uint64
+	Ino  uint64
+	Path string
+}
+
+// visitedSet records the files and directories that were already
+// searched so that hard links, bind mounts and symbolic links to them
+// are not searched again. It also stops symbolic link loops.
+type visitedSet struct {
+	mu   sync.Mutex
+	seen map[fileKey]bool
+}
+
+// The files and directories that were visited.
+var visited = &visitedSet{seen: map[fileKey]bool{}}
+
+// newFileKey returns the key of a file.
+func newFileKey(path string, stat os.FileInfo) fileKey {
+	if dev, ino, ok := fileIdentity(stat); ok {
+		return fileKey{Dev: dev, Ino: ino}
+	}
+	if canon, err := fileSystem.CanonicalPath(path); err == nil {
+		return fileKey{Path: canon}
+	}
+	return fileKey{Path: filepath.Clean(path)}
+}
+
+// firstVisit records the file and returns true if it was not visited
+// before.
+func (v *visitedSet) firstVisit(path string, stat os.FileInfo) bool {
+	key := newFileKey(path, stat)
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.seen[key] {
+		return false
+	}
+	v.seen[key] = true
+	return true
+}
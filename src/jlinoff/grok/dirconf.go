@@ -0,0 +1,122 @@
+// Directory conf files.
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// The name of the conf file that is read in each directory that is
+// walked.
+const dirConfName = ".grok.conf"
+
+// findDirConf returns the path to the directory conf file or an empty
+// string if the directory entries do not have one.
+func findDirConf(path string, entries []os.DirEntry) string {
+	for _, entry := range entries {
+		if entry.Name() == dirConfName && entry.IsDir() == false {
+			return filepath.Join(path, dirConfName)
+		}
+	}
+	return ""
+}
+
+// loadDirConf applies the options in a directory conf file to the
+// options of the parent directory. The result is used for the subtree.
+// Only the options that select files and prune directories are
+// allowed, they are added to the ones that are already set. The globs
+// are relative to the directory of the conf file.
+// The parent options are returned unchanged if there is an error.
+func loadDirConf(opts cliOptions, dir string, conf string) (cliOptions, error) {
+	args, origins, err := readConfFile(fileSystem, conf, "", map[string]string{})
+	if err != nil {
+		return opts, err
+	}
+
+	// The slices are shared with the parent and the sibling directories
+	// so they are always copied when they are extended, see the
+	// [:n:n] slice expressions.
+	sub := opts
+	for i := 0; i < len(args); i++ {
+		j := i
+		arg := args[i]
+		inline := false
+		if k := strings.Index(arg, "="); k > 2 && strings.HasPrefix(arg, "--") {
+			// Split --name=value into two arguments like
+			// loadCliOptions does.
+			args = append(args[:i+1], append([]string{arg[k+1:]}, args[i+1:]...)...)
+			origins = append(origins[:i+1], append([]string{origins[i]}, origins[i+1:]...)...)
+			arg = arg[:k]
+			inline = true
+		}
+		switch arg {
+		case "-b", "--binary":
+			// The MODE argument is optional so it must be specified
+			// inline: --binary=MODE.
+			sub.BinaryMode = "text"
+			if inline {
+				sub.BinaryMode = cliGetNextArgChoice(&i, args, binaryModes, &err)
+			}
+		case "-B", "--binary-size":
+			sub.BinarySize = cliGetNextArgCount(&i, args, &err)
+		case "--skip-generated":
+			sub.SkipGenerated = true
+		case "--encoding":
+			sub.Encoding = cliGetNextArgChoice(&i, args, encodings, &err)
+		case "-e", "--exclude":
+			n := len(sub.ExcludeOrPatterns)
+			sub.ExcludeOrPatterns = append(sub.ExcludeOrPatterns[:n:n], cliGetNextArgRegexp(&i, args, &err))
+		case "-E", "--Exclude", "--EXCLUDE":
+			n := len(sub.ExcludeAndPatterns)
+			sub.ExcludeAndPatterns = append(sub.ExcludeAndPatterns[:n:n], cliGetNextArgRegexp(&i, args, &err))
+		case "-g", "--glob":
+			n := len(sub.Globs)
+			sub.Globs = append(sub.Globs[:n:n], dirGlob(cliGetNextArgGlob(&i, args, &err), dir))
+		case "-i", "--include":
+			n := len(sub.IncludeOrPatterns)
+			sub.IncludeOrPatterns = append(sub.IncludeOrPatterns[:n:n], cliGetNextArgRegexp(&i, args, &err))
+		case "-I", "--Include", "--INCLUDE":
+			n := len(sub.IncludeAndPatterns)
+			sub.IncludeAndPatterns = append(sub.IncludeAndPatterns[:n:n], cliGetNextArgRegexp(&i, args, &err))
+		case "-p", "--prune":
+			n := len(sub.PruneOrPatterns)
+			sub.PruneOrPatterns = append(sub.PruneOrPatterns[:n:n], cliGetNextArgRegexp(&i, args, &err))
+		case "--Prune", "--PRUNE":
+			n := len(sub.PruneAndPatterns)
+			sub.PruneAndPatterns = append(sub.PruneAndPatterns[:n:n], cliGetNextArgRegexp(&i, args, &err))
+		case "--prune-glob":
+			n := len(sub.PruneGlobs)
+			sub.PruneGlobs = append(sub.PruneGlobs[:n:n], dirGlob(cliGetNextArgGlob(&i, args, &err), dir))
+		case "--prune-marker":
+			n := len(sub.PruneMarkers)
+			sub.PruneMarkers = append(sub.PruneMarkers[:n:n], cliGetNextArgMarker(&i, args, &err))
+		case "-t", "--type":
+			n := len(sub.Types)
+			sub.Types = append(sub.Types[:n:n], cliGetNextArg(&i, args, &err))
+			if err == nil {
+				err = checkFileTypes(sub.Types[n:])
+			}
+		case "-T", "--type-not":
+			n := len(sub.TypesNot)
+			sub.TypesNot = append(sub.TypesNot[:n:n], cliGetNextArg(&i, args, &err))
+			if err == nil {
+				err = checkFileTypes(sub.TypesNot[n:])
+			}
+		default:
+			err = optionErrorf("'%v' is not allowed in a directory conf file", arg)
+		}
+		if err != nil {
+			return opts, optionErrorf("%v: %v", origins[j], err)
+		}
+	}
+	return sub, nil
+}
+
+// dirGlob makes a glob relative to the directory of a conf file.
+func dirGlob(g *globPattern, dir string) *globPattern {
+	if g != nil {
+		g.Dir = dir
+	}
+	return g
+}
@@ -0,0 +1,150 @@
+// Character encodings.
+package main
+
+import (
+	"bytes"
+	"io"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// The valid values of --encoding:
+//
+//	auto          the byte order mark decides, files without one are
+//	              searched as they are (UTF-8 or ASCII)
+//	utf-8         files without a byte order mark are UTF-8
+//	utf-16le      files without a byte order mark are UTF-16LE
+//	utf-16be      files without a byte order mark are UTF-16BE
+//	latin-1       files without a byte order mark are ISO-8859-1
+//	windows-1252  files without a byte order mark are Windows-1252
+//
+// A byte order mark always wins because it is explicit. UTF-32 is only
+// recognized by its byte order mark.
+var encodings = []string{"auto", "utf-8", "utf-16le", "utf-16be", "latin-1", "windows-1252"}
+
+// The length of the longest byte order mark. At least this much of a
+// file is read to find it.
+const maxBOMSize = 4
+
+// The Windows-1252 characters from 0x80 to 0x9F. The bytes that are
+// not defined are mapped to the same control characters as Latin-1.
+var windows1252 = [32]rune{
+	0x20AC, 0x0081, 0x201A, 0x0192, 0x201E, 0x2026, 0x2020, 0x2021,
+	0x02C6, 0x2030, 0x0160, 0x2039, 0x0152, 0x008D, 0x017D, 0x008F,
+	0x0090, 0x2018, 0x2019, 0x201C, 0x201D, 0x2022, 0x2013, 0x2014,
+	0x02DC, 0x2122, 0x0161, 0x203A, 0x0153, 0x009D, 0x017E, 0x0178,
+}
+
+// fileEncoding returns the encoding of a file and the length of its
+// byte order mark from the head of the file.
+func fileEncoding(opts cliOptions, head []byte) (string, int) {
+	if bom := findByteOrderMark(head); bom != nil {
+		return bom.Encoding, len(bom.BOM)
+	}
+	if opts.Encoding == "auto" {
+		return "utf-8", 0
+	}
+	return opts.Encoding, 0
+}
+
+// decodeBytes transcodes the data to UTF-8.
+func decodeBytes(data []byte, enc string) []byte {
+	out, _ := io.ReadAll(newDecodingReader(bytes.NewReader(data), enc))
+	return out
+}
+
+// decodingReader transcodes a file to UTF-8 as it is read. The bytes
+// that cannot be decoded are replaced by U+FFFD.
+type decodingReader struct {
+	r         io.Reader
+	enc       string
+	buf       []byte // read buffer
+	in        []byte // bytes that were read but not decoded
+	out       []byte // decoded bytes that were not returned
+	surrogate rune   // the UTF-16 high surrogate of a pair
+	err       error
+}
+
+// newDecodingReader returns a reader that transcodes r from the
+// encoding to UTF-8. UTF-8 is returned as it is.
+func newDecodingReader(r io.Reader, enc string) io.Reader {
+	if enc == "utf-8" {
+		return r
+	}
+	return &decodingReader{r: r, enc: enc, buf: make([]byte, 32*1024)}
+}
+
+func (d *decodingReader) Read(p []byte) (int, error) {
+	for len(d.out) == 0 {
+		if d.err != nil {
+			if len(d.in) == 0 && d.surrogate == 0 {
+				return 0, d.err
+			}
+			// The file ends in the middle of a character.
+			d.out = utf8.AppendRune(d.out[:0], utf8.RuneError)
+			d.in = d.in[:0]
+			d.surrogate = 0
+			break
+		}
+		n, err := d.r.Read(d.buf)
+		d.in = append(d.in, d.buf[:n]...)
+		d.err = err
+		d.decode()
+	}
+	n := copy(p, d.out)
+	d.out = d.out[n:]
+	return n, nil
+}
+
+// decode transcodes the complete characters in d.in to d.out.
+func (d *decodingReader) decode() {
+	in := d.in
+	out := d.out[:0]
+	switch d.enc {
+	case "utf-16le", "utf-16be":
+		for ; len(in) >= 2; in = in[2:] {
+			r := rune(in[0]) | rune(in[1])<<8
+			if d.enc == "utf-16be" {
+				r = rune(in[0])<<8 | rune(in[1])
+			}
+			if d.surrogate != 0 {
+				pair := utf16.DecodeRune(d.surrogate, r)
+				d.surrogate = 0
+				if pair != utf8.RuneError {
+					out = utf8.AppendRune(out, pair)
+					continue
+				}
+				out = utf8.AppendRune(out, utf8.RuneError)
+			}
+			if r >= 0xD800 && r < 0xDC00 {
+				d.surrogate = r
+				continue
+			}
+			out = utf8.AppendRune(out, r)
+		}
+	case "utf-32le", "utf-32be":
+		for ; len(in) >= 4; in = in[4:] {
+			r := rune(in[0]) | rune(in[1])<<8 | rune(in[2])<<16 | rune(in[3])<<24
+			if d.enc == "utf-32be" {
+				r = rune(in[0])<<24 | rune(in[1])<<16 | rune(in[2])<<8 | rune(in[3])
+			}
+			out = utf8.AppendRune(out, r)
+		}
+	case "latin-1":
+		for _, b := range in {
+			out = utf8.AppendRune(out, rune(b))
+		}
+		in = nil
+	case "windows-1252":
+		for _, b := range in {
+			r := rune(b)
+			if b >= 0x80 && b < 0xA0 {
+				r = windows1252[b-0x80]
+			}
+			out = utf8.AppendRune(out, r)
+		}
+		in = nil
+	}
+	d.in = append(d.in[:0], in...)
+	d.out = out
+}
@@ -0,0 +1,109 @@
+// Error reporting.
+package main
+
+import (
+	"fmt"
+)
+
+// optionError is an invalid command line option, conf file entry or
+// environment variable. It terminates the program with exit status 2.
+type optionError struct {
+	Msg string
+}
+
+func (e *optionError) Error() string {
+	return e.Msg
+}
+
+// optionErrorf creates an optionError with a formatted message.
+func optionErrorf(f string, a ...interface{}) error {
+	return &optionError{Msg: fmt.Sprintf(f, a...)}
+}
+
+// searchErrorKind is the category of a problem found during the search.
+type searchErrorKind int
+
+// The search error categories. They are reported in this order in the
+// summary, the names are pluralized there.
+const (
+	unreadableFile searchErrorKind = iota
+	unreadableDir
+	scannerError
+	brokenLink
+	fileTimeout
+	confError
+	numSearchErrorKinds
+)
+
+func (k searchErrorKind) String() string {
+	switch k {
+	case unreadableFile:
+		return "unreadable file"
+	case unreadableDir:
+		return "unreadable dir"
+	case scannerError:
+		return "scanner error"
+	case brokenLink:
+		return "broken link"
+	case fileTimeout:
+		return "file timeout"
+	case confError:
+		return "conf error"
+	}
+	return fmt.Sprintf("searchErrorKind(%d)", int(k))
+}
+
+// isWarning returns true if the category is only a warning. Warnings
+// do not change the exit status unless --warnings-as-errors is set.
+func (k searchErrorKind) isWarning() bool {
+	return k == brokenLink
+}
+
+// searchError is a problem with a file or directory that was found
+// during the search. The search continues.
+type searchError struct {
+	Kind searchErrorKind
+	Path string
+	Err  error
+}
+
+func (e *searchError) Error() string {
+	return fmt.Sprintf("%v: '%v' - %v", e.Kind, e.Path, e.Err)
+}
+
+func (e *searchError) Unwrap() error {
+	return e.Err
+}
+
+// reportSearchError prints the error as a warning and counts it in the
+// statistics.
+func reportSearchError(opts cliOptions, fs *findStats, e *searchError) {
+	warning(opts, "%v", e)
+	mutex.Lock()
+	defer mutex.Unlock()
+	fs.Errors[e.Kind]++
+}
+
+// exitStatus returns the exit status like grep: 0 if there was a match,
+// 1 if there was none and 2 if there were errors. Warnings are errors
+// for --warnings-as-errors. Errors are ignored in quiet mode if there was
+// a match.
+// A search that was stopped by --timeout or by a broken pipe is an error
+// because the files that were not searched may have matched.
+func exitStatus(opts cliOptions, fs findStats, partial error) int {
+	if opts.Quiet && fs.FilesMatched > 0 {
+		return 0
+	}
+	if partial == errTimeout || partial == errBrokenPipe {
+		return 2
+	}
+	for k, n := range fs.Errors {
+		if n > 0 && (searchErrorKind(k).isWarning() == false || opts.WarningsAsErrors) {
+			return 2
+		}
+	}
+	if fs.FilesMatched > 0 {
+		return 0
+	}
+	return 1
+}
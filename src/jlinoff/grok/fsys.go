@@ -0,0 +1,113 @@
+// File system abstraction.
+package main
+
+import (
+	"archive/zip"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// searchFS is the file system that is searched. It has what the walk
+// and the file checks need: the file info with the modification time,
+// the link info to tell broken links apart, the directory entries and
+// the file contents. The paths use the native separator.
+type searchFS interface {
+	// Stat returns the file info, links are followed.
+	Stat(name string) (os.FileInfo, error)
+	// Lstat returns the file info, links are not followed.
+	Lstat(name string) (os.FileInfo, error)
+	// ReadDir returns the directory entries sorted by name.
+	ReadDir(name string) ([]os.DirEntry, error)
+	// Open opens a file for reading.
+	Open(name string) (fs.File, error)
+	// CanonicalPath returns the path without links, . and .. that
+	// identifies a file.
+	CanonicalPath(name string) (string, error)
+}
+
+// The file system that is searched. It is the disk unless --zip is
+// specified.
+var fileSystem searchFS = osFS{}
+
+// osFS is the file system of the operating system.
+type osFS struct{}
+
+func (osFS) Stat(name string) (os.FileInfo, error)      { return os.Stat(name) }
+func (osFS) Lstat(name string) (os.FileInfo, error)     { return os.Lstat(name) }
+func (osFS) ReadDir(name string) ([]os.DirEntry, error) { return os.ReadDir(name) }
+func (osFS) Open(name string) (fs.File, error)          { return os.Open(name) }
+func (osFS) CanonicalPath(name string) (string, error)  { return getCanonicalPath(name) }
+
+// ioFS searches an io/fs file system like an embed.FS, a zip archive or
+// an fstest.MapFS. Links are only reported if it implements
+// fs.ReadLinkFS. The native paths are converted to the io/fs form:
+// slash separated and relative to the top, which is ".".
+type ioFS struct {
+	FS fs.FS
+}
+
+// ioPath converts a native path to an io/fs path.
+func ioPath(name string) string {
+	p := path.Clean(filepath.ToSlash(name))
+	p = strings.TrimLeft(p, "/")
+	if len(p) == 0 {
+		return "."
+	}
+	return p
+}
+
+func (f ioFS) Stat(name string) (os.FileInfo, error) {
+	return fs.Stat(f.FS, ioPath(name))
+}
+
+func (f ioFS) Lstat(name string) (os.FileInfo, error) {
+	if lfs, ok := f.FS.(fs.ReadLinkFS); ok {
+		return lfs.Lstat(ioPath(name))
+	}
+	return f.Stat(name)
+}
+
+func (f ioFS) ReadDir(name string) ([]os.DirEntry, error) {
+	return fs.ReadDir(f.FS, ioPath(name))
+}
+
+func (f ioFS) Open(name string) (fs.File, error) {
+	return f.FS.Open(ioPath(name))
+}
+
+func (f ioFS) CanonicalPath(name string) (string, error) {
+	p := ioPath(name)
+	if _, err := fs.Stat(f.FS, p); err != nil {
+		return "", err
+	}
+	if p == "." {
+		return string(filepath.Separator), nil
+	}
+	return filepath.FromSlash("/" + p), nil
+}
+
+// openZipFS opens a zip archive for --zip. It stays open until the
+// program exits.
+func openZipFS(archive string) (searchFS, error) {
+	r, err := zip.OpenReader(archive)
+	if err != nil {
+		return nil, err
+	}
+	return ioFS{FS: r}, nil
+}
+
+// archivePath returns the path of a file in the --zip archive as it is
+// reported on the disk: the absolute path of the archive followed by the
+// path in the archive, like /tmp/release.zip/docs/a.txt.
+func archivePath(archive string, name string) string {
+	if abs, err := filepath.Abs(archive); err == nil {
+		archive = abs
+	}
+	if p := ioPath(name); p != "." {
+		return filepath.Join(archive, filepath.FromSlash(p))
+	}
+	return archive
+}
@@ -0,0 +1,97 @@
+// Generated, minified and vendored file detection.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// generatedKind is the heuristic that recognized a generated file.
+type generatedKind int
+
+// The generated file heuristics. They are reported in this order in the
+// summary.
+const (
+	goGenerated generatedKind = iota
+	markedGenerated
+	minifiedFile
+	vendoredFile
+	numGeneratedKinds
+)
+
+func (k generatedKind) String() string {
+	switch k {
+	case goGenerated:
+		return "go generated"
+	case markedGenerated:
+		return "@generated"
+	case minifiedFile:
+		return "minified"
+	case vendoredFile:
+		return "vendored"
+	}
+	return fmt.Sprintf("generatedKind(%d)", int(k))
+}
+
+// The number of bytes at the start of a file that are checked.
+const generatedHeadSize = 4096
+
+// A file whose lines are longer than this on average is minified. The
+// start of the file must be at least this long.
+const minifiedLineLength = 300
+
+// The header of generated go files, see https://go.dev/s/generatedcode.
+var goGeneratedRe = regexp.MustCompile(`(?m)^// Code generated .* DO NOT EDIT\.\r?$`)
+
+// The @generated marker, it must be in a comment at the start of a line
+// so that code that mentions it is not skipped.
+var markedGeneratedRe = regexp.MustCompile(`(?m)^\s*(//|#|/?\*|--|;+|<!--)\s*@generated\b`)
+
+// The directories that hold vendored code.
+var vendorDirs = map[string]bool{
+	"bower_components": true,
+	"jspm_packages":    true,
+	"node_modules":     true,
+	"third_party":      true,
+	"vendor":           true,
+}
+
+// isVendored returns true if the file is in a vendor directory below the
+// root.
+func isVendored(root string, path string) bool {
+	rel := relativePath(root, path)
+	dirs := strings.Split(filepath.ToSlash(filepath.Dir(rel)), "/")
+	for _, dir := range dirs {
+		if vendorDirs[dir] {
+			return true
+		}
+	}
+	return false
+}
+
+// detectGenerated checks the start of a file for the generated headers
+// and for minified content. It returns false if none of them match.
+func detectGenerated(head []byte) (generatedKind, bool) {
+	if goGeneratedRe.Match(head) {
+		return goGenerated, true
+	}
+	if markedGeneratedRe.Match(head) {
+		return markedGenerated, true
+	}
+	if len(head) >= minifiedLineLength && len(head)/(bytes.Count(head, []byte("\n"))+1) > minifiedLineLength {
+		return minifiedFile, true
+	}
+	return 0, false
+}
+
+// skipGenerated reports a file that is skipped by --skip-generated and
+// counts it in the statistics.
+func skipGenerated(opts cliOptions, fs *findStats, path string, kind generatedKind) {
+	infov2(opts, "rejecting generated file (%v): '%v'", kind, path)
+	mutex.Lock()
+	defer mutex.Unlock()
+	fs.Generated[kind]++
+}
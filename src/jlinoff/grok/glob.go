@@ -0,0 +1,178 @@
+// Glob patterns.
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// globPattern is a file name glob that is matched against the path
+// relative to the search root. The syntax is like .gitignore:
+//
+//	a*b     * is any sequence of characters except /
+//	a?b     ? is any single character except /
+//	a/**/b  ** is any sequence of directories, like **/*.go
+//	[a-z]   a character class, [!a-z] or [^a-z] negates it
+//	{a,b}   one of the alternatives
+//	\x      the character x
+//
+// A glob without a / is matched against the base name, otherwise it is
+// matched against the whole relative path. A leading / anchors it to
+// the root. A leading ! negates the pattern.
+// The globs of a directory conf file are relative to its directory
+// instead of the search root, Dir is set for them.
+type globPattern struct {
+	Glob     string
+	Negate   bool
+	BaseName bool
+	Dir      string
+	Re       *regexp.Regexp
+}
+
+// compileGlob compiles a glob into a regular expression.
+func compileGlob(glob string) (*globPattern, error) {
+	g := &globPattern{Glob: glob}
+	s := glob
+	if strings.HasPrefix(s, "!") {
+		g.Negate = true
+		s = s[1:]
+	}
+	g.BaseName = strings.Contains(s, "/") == false
+	s = strings.TrimPrefix(s, "/")
+	if len(s) == 0 {
+		return nil, fmt.Errorf("empty glob")
+	}
+
+	var sb strings.Builder
+	sb.WriteString("^")
+	braces := 0 // nesting depth of {a,b}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch c {
+		case '*':
+			if strings.HasPrefix(s[i:], "**") {
+				// ** only crosses directories as a whole component.
+				atStart := i == 0 || s[i-1] == '/'
+				if atStart && strings.HasPrefix(s[i:], "**/") {
+					sb.WriteString("(?:.*/)?")
+					i += 2
+				} else if atStart && i+2 == len(s) {
+					sb.WriteString(".*")
+					i++
+				} else {
+					return nil, fmt.Errorf("** must be a whole path component in '%v'", glob)
+				}
+			} else {
+				sb.WriteString("[^/]*")
+			}
+		case '?':
+			sb.WriteString("[^/]")
+		case '[':
+			k := i + 1
+			if k < len(s) && (s[k] == '!' || s[k] == '^') {
+				k++
+			}
+			if k < len(s) && s[k] == ']' {
+				k++ // a leading ] is literal
+			}
+			for k < len(s) && s[k] != ']' {
+				k++
+			}
+			if k >= len(s) {
+				return nil, fmt.Errorf("missing ']' in '%v'", glob)
+			}
+			class := s[i+1 : k]
+			if class[0] == '!' || class[0] == '^' {
+				class = "^" + class[1:]
+			}
+			sb.WriteString("[" + strings.Replace(class, `\`, `\\`, -1) + "]")
+			i = k
+		case '{':
+			braces++
+			sb.WriteString("(?:")
+		case ',':
+			if braces > 0 {
+				sb.WriteString("|")
+			} else {
+				sb.WriteString(",")
+			}
+		case '}':
+			if braces == 0 {
+				return nil, fmt.Errorf("unexpected '}' in '%v'", glob)
+			}
+			braces--
+			sb.WriteString(")")
+		case '\\':
+			i++
+			if i >= len(s) {
+				return nil, fmt.Errorf("trailing '\\' in '%v'", glob)
+			}
+			sb.WriteString(regexp.QuoteMeta(s[i : i+1]))
+		default:
+			sb.WriteString(regexp.QuoteMeta(s[i : i+1]))
+		}
+	}
+	if braces > 0 {
+		return nil, fmt.Errorf("missing '}' in '%v'", glob)
+	}
+	sb.WriteString("$")
+	re, err := regexp.Compile(sb.String())
+	if err != nil {
+		return nil, fmt.Errorf("invalid glob '%v': %v", glob, err)
+	}
+	g.Re = re
+	return g, nil
+}
+
+// match returns true if the relative path matches the glob, the
+// negation is ignored.
+func (g *globPattern) match(rel string) bool {
+	rel = filepath.ToSlash(rel)
+	if g.BaseName {
+		rel = rel[strings.LastIndex(rel, "/")+1:]
+	}
+	return g.Re.MatchString(rel)
+}
+
+// matchGlobs checks the path against the globs. It returns whether one
+// of the positive globs matched and whether one of the negated globs
+// matched.
+func matchGlobs(globs []*globPattern, root string, path string) (matched bool, negated bool) {
+	rootRel := relativePath(root, path)
+	for _, g := range globs {
+		rel := rootRel
+		if len(g.Dir) > 0 {
+			rel = relativePath(g.Dir, path)
+		}
+		if g.match(rel) {
+			if g.Negate {
+				negated = true
+			} else {
+				matched = true
+			}
+		}
+	}
+	return
+}
+
+// hasPositiveGlobs returns true if any of the globs is not negated.
+func hasPositiveGlobs(globs []*globPattern) bool {
+	for _, g := range globs {
+		if g.Negate == false {
+			return true
+		}
+	}
+	return false
+}
+
+// relativePath returns the path relative to the search root. If the
+// root is the path, like a file on the command line, the base name is
+// returned.
+func relativePath(root string, path string) string {
+	if rel, err := filepath.Rel(root, path); err == nil && rel != "." {
+		return rel
+	}
+	return filepath.Base(path)
+}
@@ -22,6 +22,13 @@ DESCRIPTION
     but you can explicitly specify the directories or files that
     you want to search.
 
+    Each file is only searched once. Files that are reached through
+    more than one path, like nested directories on the command line,
+    hard links and symbolic links, are only searched through the
+    first one. The directories and files on the command line are
+    always walked even if they are hidden, pruned or too deep for
+    the walk of another directory.
+
     It is similar to doing a find/grep but the regular expressions
     are more powerful, the file name appears before the file content
     and multiple expressions can be search for simultaneously. Note
@@ -89,15 +96,69 @@ DESCRIPTION
     The -i says to only include the files that have the specified
     extensions.
 
+    The file types make that simpler. These options select the same
+    files, plus python scripts that do not have an extension:
+
+            -t c -t java -t py
+
     The regular expression syntax is the same used by go. It is
     described here: https://github.com/google/re2/wiki/Syntax.
 
+GLOBS
+    The -g and --prune-glob globs have the .gitignore syntax:
+
+        *       any sequence of characters except /
+        ?       any single character except /
+        **      any sequence of directories, like src/**/*.go
+        [a-z]   a character class, [!a-z] or [^a-z] negates it
+        {a,b}   one of the alternatives, like *.{c,h}
+        \x      the character x
+
+    A glob without a / is matched against the file or directory name,
+    otherwise it is matched against the whole relative path. A
+    leading / anchors a glob without another / to the top directory,
+    like /*.md.
+
+CONFIG FILES
+    Before the command line arguments are parsed, default arguments
+    are read from these sources in order:
+
+        1. $XDG_CONFIG_HOME/grok/config (~/.config/grok/config)
+        2. the nearest .grokrc in the current directory or a parent
+        3. the GROK_OPTS environment variable
+
+    The config files have the conf file syntax (see -c), GROK_OPTS is
+    split the same way. Later sources take precedence: an option
+    that takes a single value, like -z, is overridden by a later
+    one while patterns, like -a, are accumulated. The command line
+    always has the last word. Use --no-config to ignore all of
+    them. Use -v to see which config files were read and -vv to see
+    where each option came from.
+
+    A .grok.conf file in a directory that is searched changes the
+    options for that directory and its subdirectories. It is read
+    when the directory is entered and its options are added to the
+    ones of the parent directory. Only these options are allowed:
+    -b, --binary=MODE, -B, -e, -E, --encoding, -g, -i, -I, -p,
+    --Prune, --prune-glob, --prune-marker, --skip-generated, -t and
+    -T. The globs are relative to the directory of the .grok.conf
+    file. Here is an example for a directory with generated code:
+
+        # gen/.grok.conf
+        -e '\.pb\.go$'
+        --prune-glob old
+
+    The .grok.conf files are ignored for --no-config.
+
 DATE/TIME SPECIFICATION
-    The date/time specification is used by the -n and -o options
-    to specify a relative date time. A specification consists of a
-    positive integer with a suffix to indicate seconds, minutes,
-    hours, days and weeks. To analyze all files that have been
-    modified in the last week you would specify -n 1w or -n 7d.
+    The date/time specification is used by the -n and -o options.
+    It can be relative, a keyword or absolute.
+
+    A relative specification consists of a positive integer with a
+    suffix to indicate seconds, minutes, hours, days and weeks. It
+    is subtracted from the current time. To analyze all files that
+    have been modified in the last week you would specify -n 1w or
+    -n 7d.
 
     The table below lists the suffixes.
 
@@ -111,6 +172,18 @@ DATE/TIME SPECIFICATION
 
     If no suffix is specified, seconds are assumed.
 
+    The keywords are now, today (midnight) and yesterday (midnight
+    of the previous day).
+
+    An absolute specification is an ISO 8601 or RFC 3339 date and
+    an optional time separated by a 'T' or a space. The time zone is
+    Z or an offset like +01:00, +0100 or +01. If there is no time
+    zone, the local time zone is used. For example:
+        2017-03-01
+        2017-03-01T13:00
+        2017-03-01 13:00:00
+        2017-03-01T13:00:00.250-08:00
+
     You can search time windows by using both options. Here is an
     example that shows how to search files that are newer than 4
     weeks but older than 2 weeks:
@@ -121,6 +194,9 @@ DATE/TIME SPECIFICATION
     time window.
 
 OPTIONS
+    Long options that take an argument can also be specified as
+    --name=value, for example: --max-depth=2.
+
     -a REGEXP, --accept REGEXP
                        Accept if the contents match the regular
                        expression.
@@ -150,29 +226,57 @@ OPTIONS
     --after NUM, -z NUM
                        Print NUM lines after the match.
 
-    --before NUM, -y NUM
-                       Print NUM lines before the match.
-
-    -b, --binary       Search binary files.
+                       Context lines after a match are prefixed by
+                       |+ and context lines before a match are
+                       prefixed by |-. Matched lines keep their line
+                       number prefix.
 
-                       By default binary files are skipped.
-                       The binary test is not very sophisticated.
+                       The context windows of matches that are close
+                       together are merged into a single hunk so that
+                       no line is printed more than once. Hunks are
+                       separated by a single separator line.
 
-                       It may designate UTF based document files as
-                       binary.
+    --before NUM, -y NUM
+                       Print NUM lines before the match.
+                       See --after for how the context is reported.
+
+    -b, --binary, --binary=MODE
+                       Select how binary files are handled:
+                           skip   they are not searched (default)
+                           match  they are searched but the matched
+                                  lines are replaced by a "binary file
+                                  PATH matches" note like grep does
+                           text   they are searched like text files
+                       -b and --binary without a MODE are text, the
+                       MODE must be specified inline.
+
+                       A file that starts with a Unicode byte order
+                       mark is text. Otherwise it is binary if it has
+                       a NUL byte, if more than 30%% of the bytes are
+                       not valid UTF-8 or if more than 10%% of them
+                       are control characters other than white space,
+                       backspace and escape. Files without a newline
+                       and files in 8-bit encodings like Latin-1 are
+                       text.
 
     -B INT, --binary-size INT
                        Number of bytes to read to determine whether
                        this is a binary file.
-                       The default is 512.
+                       The default is 1024.
 
     -c CONF, --conf CONF
                        Read a conf file and insert the arguments
                        directly into the command line. This is
                        convenient for storing and re-using common
-                       options. The syntax is simple, each line is
-                       a single option. Blank lines and lines that
-                       start with a hash are ignore.
+                       options. Each line contains any number of
+                       arguments that are split like the shell does:
+                       single and double quotes group white space,
+                       $VAR and ${VAR} are expanded outside of single
+                       quotes and a backslash escapes white space,
+                       quotes, $, # and itself. Other backslashes are
+                       kept so regular expressions like \bfoo\b work
+                       without quotes. Blank lines and text that
+                       starts with a hash are ignored.
 
                        Here is an example that prunes .git and .repo
                        files:
@@ -184,11 +288,56 @@ OPTIONS
                        When this file is read, it is exactly like
                        specifying those options on the command line.
 
-                       Nested conf files can be specified but the
-                       program aborts if it finds nested references
-                       to the same conf file.
+                       Nested conf files can be specified with -c or
+                       with the include directive:
+                           include PATH
+                       A relative -c or include path is relative to
+                       the directory of the conf file. The program
+                       aborts if it finds nested references to the
+                       same conf file. Errors report the file and line
+                       number.
+
+    -C, --color, --color=WHEN
+                       Colorize the output using ANSI escape sequences.
+                       WHEN is auto, always or never. The default is
+                       auto. -C and --color are the same as always.
+
+                       In auto mode the output is only colorized if
+                       stdout is a terminal. Setting the NO_COLOR
+                       environment variable to a non-empty value
+                       disables colors and setting CLICOLOR_FORCE to a
+                       value other than 0 forces them. Both are
+                       ignored if always or never is specified.
+
+                       The matches of each accept pattern are shown
+                       in their own color. If the matches of two
+                       patterns overlap, the one that starts first
+                       wins and, if they start at the same place, the
+                       longest one wins.
+
+                       The colors can be changed with the GROK_COLORS
+                       environment variable. It is a colon separated
+                       list of NAME=CODE entries where CODE is an ANSI
+                       SGR code like 1 (bold) or 38;5;245 (gray). An
+                       empty code disables the color. These are the
+                       names and their defaults:
+
+                           path     file names        1
+                           line     line numbers      38;5;245
+                           sep      separators        38;5;245
+                           context  context lines     38;5;245
+                           match    matches           31;1,32;1,34;1,35;1,36;1,33;1
+
+                       The match entry is a comma separated palette,
+                       the first accept pattern uses the first color,
+                       the second uses the second color and so on.
+                       Here is an example:
+                           $ GROK_COLORS='path=35:match=4,7' %[1]v -C -l -a foo -a bar
 
-    --color            Colorize the output using ANSI escape sequences.
+    --column           Print the column number of the first accept
+                       match after the line number when the lines are
+                       reported without a heading (--no-heading).
+                       Columns are byte offsets starting at 1.
 
     -d REGEXP, --delete REGEXP
                        Delete accepted entries if the contents match
@@ -220,6 +369,38 @@ OPTIONS
                            $ %[1]v -A foo -A bar -D spam -D wombat
                            test/foobar
 
+    --dir-include REGEXP
+                       Only search the files in the directories that
+                       match the regular expression and in their
+                       subdirectories. The other directories are still
+                       walked to find the ones that match but their
+                       files are skipped. If multiple patterns are
+                       specified, only one of them has to match.
+                       Here is an example that only searches the
+                       generated code:
+                           $ %[1]v --dir-include '/gen$' -a FOOBAR
+
+    --encoding ENCODING
+                       The character encoding of the files that do not
+                       start with a byte order mark:
+                           auto          search them as they are,
+                                         like UTF-8 (default)
+                           utf-8         UTF-8
+                           utf-16le      UTF-16 little endian
+                           utf-16be      UTF-16 big endian
+                           latin-1       ISO-8859-1
+                           windows-1252  Windows code page 1252
+                       A byte order mark always wins, it selects
+                       UTF-8, UTF-16 or UTF-32. The files in other
+                       encodings are transcoded to UTF-8 before they
+                       are checked for binary content and matched so
+                       the patterns and the printed lines are UTF-8.
+                       The byte order mark is not printed. Use -v to
+                       see the original encoding of the matched files.
+                       Here is an example that searches the Windows
+                       files in a directory:
+                           $ %[1]v --encoding windows-1252 -a 'café'
+
     -e REGEXP, --exclude REGEXP
                        Exclude file if the name matches the regular
                        expression.
@@ -244,8 +425,55 @@ OPTIONS
                            test/fooonly
                            test/baronly
 
+    --file-timeout DURATION
+                       Give up on a file if it cannot be read within
+                       the duration. A warning is printed and the
+                       search continues with the next file. This keeps
+                       a hung file, like a file on a dead NFS server,
+                       from stalling the whole search.
+                       Durations look like 500ms, 30s, 2m or 1h.
+
+    -g GLOB, --glob GLOB
+                       Include files that match the glob. A glob that
+                       starts with ! excludes the files that match it
+                       like -e. The globs are matched against the path
+                       relative to the directory that is searched so
+                       they do not depend on how it was specified.
+                       See GLOBS for the syntax. Here is an example
+                       that searches the javascript files except the
+                       minified ones:
+                           $ %[1]v -g '*.js' -g '!*.min.js' -a FOO
+
     -h, --help         On-line help.
 
+    --hidden           Search hidden files and directories, the ones
+                       whose names start with a dot. They are skipped
+                       by default unless they are specified on the
+                       command line. See --no-hidden.
+
+    --hyperlink        Make the file names clickable in terminals that
+                       support OSC 8 hyperlinks. It is ignored if the
+                       output is not colorized. For --zip the links
+                       point to the archive.
+
+    --heading          Print the file name on its own line before the
+                       matched lines. This is the default.
+
+    --no-config        Do not read the user config file, the .grokrc
+                       file, GROK_OPTS or the .grok.conf files. See
+                       the CONFIG FILES section.
+
+    --no-heading       Print the file name and line number in front of
+                       each matched line using the grep format:
+                           path:lineno:text
+                       Context lines are reported as path-lineno-text
+                       and groups of lines are separated by --.
+                       It implies -l.
+
+    --no-hidden        Skip hidden files and directories. This is the
+                       default, it can be used to undo --hidden from a
+                       config file.
+
     -i REGEXP, --include REGEXP
                        Include file if the name matches the regular
                        expression.
@@ -274,6 +502,15 @@ OPTIONS
                            test/foobar
                            test/nofoobar
 
+    --invert-lines     Report the lines that do not match in the files
+                       that qualify. The files are still selected by
+                       the accept, reject and delete criteria, only
+                       the reported lines are inverted.
+
+                       Here is an example that prints the lines that
+                       do not contain foo in the files that do:
+                           $ %[1]v -l --invert-lines -a foo
+
     -l, --lines        Show the lines that match.
                        If this is not specified, only the file names
                        are shown. It is useful when using the tool
@@ -291,17 +528,52 @@ OPTIONS
                        If both -L and -l are specified, a warning is
                        generated and -L is ignored.
 
+    --list-profiles    List the profiles that are defined in the conf
+                       files and exit. See -P.
+
     -m INT, --max-depth INT
                        The maximum depth in the directory tree.
                        The top level is 0.
                        The default is no maximum (0). All
                        subdirectories are processed.
 
+    --match-relative   Match the -i, -I, -e, -E, -p, --Prune and
+                       --dir-include regular expressions against the
+                       path relative to the directory that is searched
+                       instead of the path as it is reported. This
+                       makes the patterns independent of where the
+                       search starts:
+                           $ %[1]v --match-relative -p '^vendor$' src
+
+    --max-count INT    Stop reading a file after INT matched lines.
+                       The file is still read to the end if there are
+                       reject patterns because they could reject it.
+                       The default is no maximum (0).
+
     -M INT, --max-jobs INT
                        Maximum number of jobs (goroutines) to run
                        in parallel. Each job is a file analysis.
+                       Up to the same number of goroutines walk the
+                       directories in parallel. Use -M 1 to get the
+                       results in a predictable order.
                        The default is %[2]v.
 
+    --max-total INT    Stop the search after INT matches. A match is a
+                       reported line if the lines are reported (-l, -L)
+                       and a reported file otherwise. The files that
+                       are being read are abandoned and the directory
+                       walk stops.
+                       The default is no maximum (0).
+
+    --min-depth INT    Only search files that are at least INT levels
+                       deep. The files in the directories that are
+                       searched are at level 0, which is also where
+                       the files on the command line are. The
+                       directories above the minimum depth are still
+                       walked. Here is an example that only searches
+                       the nested modules of a repository:
+                           $ %[1]v --min-depth 1 -g go.mod -a '^require'
+
     -n DATE/TIME, --newer-than DATE/TIME
                        Only consider files that are newer than the
                        date/time specification. The specification
@@ -313,6 +585,11 @@ OPTIONS
                        were modified in the last day:
                            $ %[1]v -n 1d
 
+    --newer-than-file REF
+                       Only consider files that are newer than the
+                       reference file, like find -newer. The same
+                       time field (--time-field) is compared.
+
     -o DATE/TIME, --older-than DATE/TIME
                        Only consider files that are older than the
                        date/time specification. The specification
@@ -324,10 +601,42 @@ OPTIONS
                        have not been modified in the last week:
                            $ %[1]v -o 1w
 
+    --path-style STYLE How the file names are reported:
+                           as-given  the path as it was found from the
+                                     command line argument (default)
+                           relative  relative to the current directory
+                           absolute  the absolute path
+                           basename  just the file name
+                       The style does not change which files are
+                       searched or what the patterns match. For --zip
+                       the relative paths start at the top of the
+                       archive and the absolute paths at the absolute
+                       path of the archive, like /tmp/a.zip/src/x.go.
+
+    -P NAME, --profile NAME
+                       Insert the arguments of a profile that is
+                       defined in a conf file. A profile is a section
+                       that starts with a [profile NAME] line and ends
+                       at the next section or the end of the file. The
+                       arguments before the first section are always
+                       used. A profile can extend other profiles with
+                       an extends line, their arguments come first:
+
+                           -W
+                           [profile go]
+                           -i '\.go$' -p 'vendor$'
+                           [profile go-src]
+                           extends go
+                           -e '_test\.go$'
+
+                       The conf file must be read before -P, the
+                       config files are always read first.
+
     -p REGEXP, --prune REGEXP
                        Prune a directory if the path matches the
                        regular expression. By default all directories
-                       are searched.
+                       are searched. The directories on the command
+                       line are never pruned.
 
                        The prune option can be used to significantly
                        speed up analysis. It is typically used to
@@ -342,6 +651,40 @@ OPTIONS
                        well. Here is an example of that:
                            $ %[1]v -p 'project1/lib|project1/bin|project1/tools'
 
+    --Prune REGEXP, --PRUNE REGEXP
+                       Prune a directory if the path matches all of the
+                       --Prune regular expressions (an AND operation).
+                       Here is an example that prunes the build
+                       directories of the tools but not the others:
+                           $ %[1]v --Prune '/build$' --Prune '^tools/'
+
+    --prune-glob GLOB  Prune a directory if its path relative to the
+                       directory that is searched matches the glob. A
+                       glob that starts with ! keeps the directories
+                       that match it even if they would be pruned.
+                       Here is an example:
+                           $ %[1]v --prune-glob node_modules --prune-glob 'build/**'
+
+    --prune-marker NAME
+                       Prune a directory if it contains a file or
+                       directory with the name. The default markers
+                       are CACHEDIR.TAG, which must have the signature
+                       of the Cache Directory Tagging Specification,
+                       and .grokskip. The directories that are
+                       specified on the command line are never pruned
+                       by a marker. Here is an example that does not
+                       cross go module boundaries:
+                           $ %[1]v --prune-marker go.mod -a FOOBAR
+
+    --no-prune-markers Do not use the default prune markers or the
+                       ones that were specified before.
+
+    -q, --quiet        Do not print anything. Stop the search as soon
+                       as a file matches and exit with status 0. If no
+                       files match, exit with status 1. This is useful
+                       for "does this exist anywhere" checks in scripts:
+                           $ %[1]v -q -a FOOBAR src && echo found
+
     -r REGEXP, --reject REGEXP
                        Reject if the contents match the regular expression.
                        If multiple reject criterion are specified,
@@ -364,8 +707,80 @@ OPTIONS
                            test/baronly
                            test/nofoobar
 
+    --scope REGEXP     Report the enclosing scope of the matched lines.
+                       For each hunk of matched lines, the nearest
+                       preceding line that matches the regular
+                       expression is printed with an = separator:
+                                9 = def where(self):
+                               10 |     place = 'here'
+
+                       If multiple scope criterion are specified,
+                       only one of them has to match (an OR operation).
+
+                       Here is an example that shows the function
+                       that contains each match in go files:
+                           $ %[1]v -l --scope '^func ' -a foo -i '\.go$'
+
+    --scope-auto       Report the enclosing scope using the built-in
+                       pattern for the language of each file. It is
+                       chosen by the file extension. These languages
+                       are supported: C, C++, go, java, javascript,
+                       perl, php, python, ruby, rust, shell and
+                       typescript. Explicit --scope patterns take
+                       precedence.
+
+    --scope-block      Print the whole enclosing scope of each match
+                       rather than just the scope line. A scope ends
+                       just before the next scope line. This is
+                       similar to git diff -W.
+
+    --show REGEXP      Report the lines that match the regular
+                       expression in the files that qualify instead of
+                       the lines that matched the accept criteria.
+                       This allows the criteria that select a file to
+                       be different from the criteria that select the
+                       lines that are reported from it.
+
+                       If multiple show criterion are specified,
+                       only one of them has to match (an OR operation).
+
+                       Here is an example that prints the import lines
+                       of the go files that reference FOOBAR:
+                           $ %[1]v -l --show '^import|^\s+"' -a FOOBAR -i '\.go$'
+
+    --skip-generated   Skip the files that are generated, minified or
+                       vendored. They are recognized by:
+                           go generated  a "Code generated ... DO NOT
+                                         EDIT." comment line
+                           @generated    a comment that starts with
+                                         @generated
+                           minified      an average line length of
+                                         more than 300 bytes
+                           vendored      a vendor, node_modules,
+                                         third_party, bower_components
+                                         or jspm_packages directory
+                                         below the searched directory
+                       The headers and the line length are checked in
+                       the first 4096 bytes. The summary reports how
+                       many files each of them skipped.
+
     -s, --summary      Print the summary report.
 
+                       If the search is stopped by an interrupt (^C),
+                       a timeout or a closed output pipe, the summary
+                       is still printed and it is marked as partial.
+                       A second interrupt terminates the program
+                       immediately.
+
+                       Files and directories that could not be
+                       searched are counted by category: unreadable
+                       files, unreadable dirs, scanner errors, broken
+                       links and file timeouts.
+
+                       The files that were skipped because they were
+                       already searched through another path are
+                       reported as files deduped.
+
     -S INIT MAX --scan-buf-params INIT MAX
                        Set the internal scan buffer parameters to
                        handle long lines like those in some log
@@ -373,13 +788,83 @@ OPTIONS
                        10485760 (10MB). These values normally do
                        not need to be set.
 
+    --strip-prefix PREFIX
+                       Remove the prefix from the reported file names
+                       after --path-style is applied. It must end at a
+                       directory boundary, /tmp/t is not a prefix of
+                       /tmp/t2/a.txt. A separator that is left at the
+                       start is removed as well:
+                           $ %[1]v --path-style absolute --strip-prefix $HOME
+
+    --time-field FIELD
+                       The file time that -n, -o and --newer-than-file
+                       check: mtime (modification), atime (access) or
+                       ctime (status change). The default is mtime.
+                       The modification time is used on platforms
+                       that do not have the others.
+
+    -t TYPE, --type TYPE
+                       Only include files of the type. A type is a set
+                       of file name globs and, for scripts without an
+                       extension, #! interpreters. For example, -t go
+                       includes *.go files and -t py includes *.py files
+                       and python scripts. It can be specified multiple
+                       times and it is combined with the -i patterns.
+                       Use --type-list to see the types.
+
+    -T TYPE, --type-not TYPE
+                       Exclude files of the type like -e. For example,
+                       -t go -T test searches the go files that are
+                       not tests.
+
+    --type-add NAME:GLOB[,GLOB...]
+                       Define a new type or add globs to an existing
+                       one. Entries that start with #! are
+                       interpreters. For example:
+                           --type-add 'web:*.html,*.css,*.js'
+                       It is convenient in a conf file.
+
+    --type-list        List the file types and exit.
+
+    --timeout DURATION Stop the search when the duration expires. The
+                       files that are being read are abandoned and the
+                       summary is marked as partial.
+                       Durations look like 500ms, 30s, 2m or 1h.
+
     -v, --verbose      Increase the level of verbosity.
                        Can use -vv and -vvv as shorthand.
 
+    --vimgrep          Report each matched line in the format used by
+                       vim (:grep), emacs (compilation-mode) and most
+                       editor problem matchers:
+                           path:lineno:col:text
+                       It is the same as --no-heading --column -l.
+
     -V, --version      Print the program version and exit.
 
     -W, --no-warning   Do not print warnings.
 
+    --warnings-as-errors
+                       Broken links are normally only warnings. This
+                       option makes them errors so that the exit status
+                       is 2. It is useful for CI.
+
+    --zip ARCHIVE      Search the files in a zip archive instead of the
+                       disk. The directories and files are paths in the
+                       archive, the default is the top of the archive.
+                       The .grok.conf files in the archive are used.
+                       Here is an example:
+                           $ %[1]v --zip release.zip -a FOOBAR docs
+
+EXIT STATUS
+    0   A file matched.
+    1   No file matched.
+    2   There was an error, like an invalid option or a file that
+        could not be read, or the search was stopped early by
+        --timeout or by a closed output pipe. In quiet mode (-q) a
+        match still exits with 0.
+    130 The search was interrupted.
+
 EXAMPLES
     # Example 1: help
     $ %[1]v -h
@@ -457,6 +942,9 @@ EXAMPLES
     #             inserts affects the sed operation.
     $ %[1]v -WLa '^\s*#\s+include\s' -i '\.[ch]$' | sed -e 's/^[[:space:]]*@/@/g' -e 's/^#[[:space:]]*include/#include/' | sort -fu | cat -n
 
+    # Example 15: Report matches in a format that editors can parse.
+    $ vim -q <(%[1]v --vimgrep -a '\bFOOBAR\b' -i '\.[ch]$')
+
 COPYRIGHT:
    Copyright (c) 2017 Joe Linoff, all rights reserved
 
@@ -2,8 +2,9 @@ package main
 
 import (
 	"bufio"
+	"context"
 	"fmt"
-	"io/ioutil"
+	"io"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -18,6 +19,10 @@ type findStats struct {
 	FilesTested  int64
 	FilesMatched int64
 	LinesMatched int64
+	TotalMatches int64                    // used by --max-total
+	FilesDeduped int64                    // files that were already searched
+	Generated    [numGeneratedKinds]int64 // files skipped by --skip-generated
+	Errors       [numSearchErrorKinds]int64
 }
 
 // Locking semaphore for printing.
@@ -28,114 +33,245 @@ var maxgo chan bool
 
 // Need to test golint.
 func main() {
-	opts := loadCliOptions()
+	opts, err := loadCliOptions()
+	if err != nil {
+		errorMsg("%v", err)
+		os.Exit(2)
+	}
 	infov(opts, "version: %v %v", filepath.Base(os.Args[0]), version)
 	infov(opts, "cmdline: %v", opts.CmdLine)
 
 	// Setup concurrency.
 	mutex = &sync.Mutex{}
 	maxgo = make(chan bool, opts.MaxJobs)
+	maxwalk = make(chan bool, opts.MaxJobs-1)
+	ctx := newSearchContext(opts)
 
-	// Start work.
+	// Start work. A root that is inside another root is only skipped
+	// if the walk of the other root reached it.
 	fs := findStats{}
 	for _, dir := range opts.Dirs {
-		walk(opts, dir, &fs, 0)
+		if searchStopped(ctx) {
+			break
+		}
+		walk(ctx, opts, dir, dir, &fs, 0)
 	}
+	walkers.Wait()
 
 	// Wait for the jobs to finish.
 	// We will not be able to assign to all of them until all of the goroutines
-	// have finished.
+	// have finished. Once the search is stopped, the jobs that are still
+	// blocked, like a read from a FIFO, are abandoned. Their statistics
+	// are copied because the abandoned jobs may still update them.
 	for i := 0; i < cap(maxgo); i++ {
-		maxgo <- true
+		select {
+		case maxgo <- true:
+		case <-ctx.Done():
+			i = cap(maxgo)
+		}
 	}
+	mutex.Lock()
+	stats := fs
+	mutex.Unlock()
 
 	// Output the summary information.
+	// It is marked as partial if the search did not finish.
+	partial := partialReason(ctx)
 	if opts.Summary {
-		fmt.Println("")
-		fmt.Printf("summary: files tested : %8s\n", commaize(fs.FilesTested))
-		fmt.Printf("summary: files matched: %8s\n", commaize(fs.FilesMatched))
-		fmt.Printf("summary: lines matched: %8s\n", commaize(fs.LinesMatched))
+		fmt.Fprintln(stdout, "")
+		if partial != nil {
+			fmt.Fprintf(stdout, "summary: partial      : %v\n", partial)
+		}
+		fmt.Fprintf(stdout, "summary: files tested : %8s\n", commaize(stats.FilesTested))
+		fmt.Fprintf(stdout, "summary: files matched: %8s\n", commaize(stats.FilesMatched))
+		fmt.Fprintf(stdout, "summary: lines matched: %8s\n", commaize(stats.LinesMatched))
+		if stats.FilesDeduped > 0 {
+			fmt.Fprintf(stdout, "summary: files deduped: %8s\n", commaize(stats.FilesDeduped))
+		}
+		for k, n := range stats.Generated {
+			if n > 0 {
+				fmt.Fprintf(stdout, "summary: %-13s: %8s\n", generatedKind(k), commaize(n))
+			}
+		}
+		for k, n := range stats.Errors {
+			if n > 0 {
+				fmt.Fprintf(stdout, "summary: %-13s: %8s\n", searchErrorKind(k).String()+"s", commaize(n))
+			}
+		}
 	}
 
-	infov(opts, "files tested:  %8s", commaize(fs.FilesTested))
-	infov(opts, "files matched: %8s", commaize(fs.FilesMatched))
-	infov(opts, "lines matched: %8s", commaize(fs.LinesMatched))
+	infov(opts, "files tested:  %8s", commaize(stats.FilesTested))
+	infov(opts, "files matched: %8s", commaize(stats.FilesMatched))
+	infov(opts, "lines matched: %8s", commaize(stats.LinesMatched))
+	if stats.FilesDeduped > 0 {
+		infov(opts, "files deduped: %8s", commaize(stats.FilesDeduped))
+	}
 	infov(opts, "done")
-}
 
-// walk the directory tree looking for files that match.
-func walk(opts cliOptions, path string, fs *findStats, depth int) {
-	infov2(opts, "checking: %v %v '%v'", depth, opts.MaxDepth, path)
-	if opts.MaxDepth >= 0 && depth > opts.MaxDepth {
-		return
+	if partial == errInterrupted {
+		os.Exit(130) // 128 + SIGINT, like the shells
 	}
 
-	// If this is a file, process it.
-	// If it is a directory, look at all of the entries.
-	stat, err := os.Stat(path)
-	if err != nil {
-		// Normally this is just a bad link.
-		warning(opts, "%v", err)
-		return
-	}
-
-	if stat.IsDir() {
-		if pruneDir(opts, path) {
-			infov2(opts, "pruning '%v'", path)
-			return
-		}
+	os.Exit(exitStatus(opts, stats, partial))
+}
 
-		entries, err := ioutil.ReadDir(path)
-		if err != nil {
-			warning(opts, "cannot read directory: '%v' - %v", path, err)
-			return
+// pruneDir returns true if the directory path should be pruned.
+// The --prune-glob globs are matched against the path relative to the
+// root, a negated glob keeps a directory that would be pruned. The root
+// itself is never pruned because it was asked for explicitly.
+// The directory is pruned if any of the -p patterns or all of the
+// --Prune patterns match.
+func pruneDir(opts cliOptions, root string, path string) bool {
+	if path == root {
+		return false // it was specified on the command line
+	}
+	if len(opts.PruneGlobs) > 0 {
+		matched, negated := matchGlobs(opts.PruneGlobs, root, path)
+		if negated {
+			return false // explicitly kept
 		}
-
-		for _, entry := range entries {
-			newPath := filepath.Join(path, entry.Name())
-			stat, err = os.Stat(newPath)
-			if err != nil {
-				// Normally this is just a bad link.
-				warning(opts, "%v", err)
-			} else {
-				if stat.IsDir() {
-					walk(opts, newPath, fs, depth+1)
-				} else {
-					checkFileParallel(opts, newPath, stat, fs)
-				}
-			}
+		if matched {
+			return true
 		}
-	} else {
-		checkFileParallel(opts, path, stat, fs)
 	}
-}
-
-// pruneDir returns true if the directory path should be pruned.
-func pruneDir(opts cliOptions, path string) bool {
+	name := path
+	if opts.MatchRelative {
+		name = relativePath(root, path)
+	}
 	if len(opts.PruneOrPatterns) > 0 {
 		for _, p := range opts.PruneOrPatterns {
-			if p.MatchString(path) {
+			if p.MatchString(name) {
 				return true // match was found, prune it
 			}
 		}
 	}
+	if len(opts.PruneAndPatterns) > 0 {
+		for _, p := range opts.PruneAndPatterns {
+			if p.MatchString(name) == false {
+				return false
+			}
+		}
+		return true // all of them matched, prune it
+	}
 	return false // by default all directories are accepted
 }
 
-// checkFileParallel sets up the channel for parallel execution
-func checkFileParallel(opts cliOptions, path string, stat os.FileInfo, fs *findStats) {
-	fs.FilesTested++
-	maxgo <- true // reserve the slot
+// The signature that identifies a valid CACHEDIR.TAG file, see
+// https://bford.info/cachedir/.
+const cacheDirTagSignature = "Signature: 8a477f597d28d172789f06886806bc55"
+
+// pruneMarker returns the name of the first --prune-marker file in the
+// directory entries or an empty string if there is none. A CACHEDIR.TAG
+// only counts if it starts with the signature.
+func pruneMarker(opts cliOptions, path string, entries []os.DirEntry) string {
+	if len(opts.PruneMarkers) == 0 {
+		return ""
+	}
+	for _, entry := range entries {
+		for _, marker := range opts.PruneMarkers {
+			if entry.Name() != marker {
+				continue
+			}
+			if marker == "CACHEDIR.TAG" && isCacheDirTag(filepath.Join(path, marker)) == false {
+				continue
+			}
+			return marker
+		}
+	}
+	return ""
+}
+
+// isCacheDirTag returns true if the file starts with the CACHEDIR.TAG
+// signature.
+func isCacheDirTag(path string) bool {
+	file, err := fileSystem.Open(path)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+	buf := make([]byte, len(cacheDirTagSignature))
+	if _, err := io.ReadFull(file, buf); err != nil {
+		return false
+	}
+	return string(buf) == cacheDirTagSignature
+}
+
+// matchDirInclude returns true if the directory matches one of the
+// --dir-include patterns. The patterns are matched like -p.
+func matchDirInclude(opts cliOptions, root string, path string) bool {
+	name := path
+	if opts.MatchRelative {
+		name = relativePath(root, path)
+	}
+	for _, p := range opts.DirIncludePatterns {
+		if p.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkFileParallel sets up the channel for parallel execution.
+// The stat is nil for the files that were found by the walk, the job
+// reads it once the name filters have accepted the file.
+func checkFileParallel(ctx context.Context, opts cliOptions, root string, path string, stat os.FileInfo, fs *findStats) {
+	// Reserve the slot unless the search is stopped while waiting.
+	select {
+	case maxgo <- true:
+	case <-ctx.Done():
+		return
+	}
 	go func(opts cliOptions, path string, stat os.FileInfo, fs *findStats) {
-		checkFile(opts, path, stat, fs)
-		<-maxgo // give up the slot
+		defer func() { <-maxgo }() // give up the slot
+
+		// Test the include/exclude and/or patterns, the globs and the
+		// types. They only need the path.
+		if matchFileName(opts, root, path) == false {
+			infov2(opts, "rejecting file by name: '%v'", path)
+			mutex.Lock()
+			fs.FilesTested++
+			mutex.Unlock()
+			return
+		}
+
+		if stat == nil {
+			var serr *searchError
+			if stat, serr = statPath(path); serr != nil {
+				reportSearchError(opts, fs, serr)
+				return
+			}
+		}
+
+		// A file that is reachable through more than one path, like a
+		// hard link or a symbolic link, is only checked once.
+		if visited.firstVisit(path, stat) == false {
+			infov2(opts, "skipping file that was already searched: '%v'", path)
+			mutex.Lock()
+			fs.FilesDeduped++
+			mutex.Unlock()
+			return
+		}
+		mutex.Lock()
+		fs.FilesTested++
+		mutex.Unlock()
+
+		fctx := ctx
+		if opts.FileTimeout > 0 {
+			var cancel context.CancelFunc
+			fctx, cancel = context.WithTimeoutCause(ctx, opts.FileTimeout, errFileTimeout)
+			defer cancel()
+		}
+		checkFile(fctx, opts, root, path, stat, fs)
 	}(opts, path, stat, fs)
 }
 
 // checkFile checks to see whether this file matches.
-func checkFile(opts cliOptions, path string, stat os.FileInfo, fs *findStats) {
+func checkFile(ctx context.Context, opts cliOptions, root string, path string, stat os.FileInfo, fs *findStats) {
 	// This is a file that we need to check.
 	infov2(opts, "checking file: %v", path)
+	if searchStopped(ctx) {
+		return
+	}
 
 	// See if the file is out of date.
 	if validTimestamp(opts, path, stat) == false {
@@ -143,17 +279,53 @@ func checkFile(opts cliOptions, path string, stat os.FileInfo, fs *findStats) {
 		return
 	}
 
-	// Test the include/exclude and/or patterns.
-	if matchFileName(opts, path) == false {
-		infov2(opts, "rejecting file by name: '%v'", path)
+	// The vendored files are known by their path.
+	if opts.SkipGenerated && isVendored(root, path) {
+		skipGenerated(opts, fs, path, vendoredFile)
 		return
 	}
 
-	// Check to see if this is binary file.
-	if opts.Binary == false && isBinary(opts, path, stat) {
-		infov2(opts, "rejecting binary file: '%v'", path)
+	// Check the encoding and whether this is a binary or generated
+	// file. The checks look at the start of the file, it is only read
+	// once. A file in another encoding is transcoded to UTF-8 first so
+	// that UTF-16 is not taken for binary.
+	// For --binary=match the binary files are searched but their lines
+	// are not printed.
+	size := opts.BinarySize
+	if opts.SkipGenerated && size < generatedHeadSize {
+		size = generatedHeadSize
+	}
+	if size < maxBOMSize {
+		size = maxBOMSize
+	}
+	head, err := readHead(ctx, path, size)
+	if searchStopped(ctx) {
+		abandonFile(ctx, opts, path, fs)
 		return
 	}
+	if err != nil {
+		reportSearchError(opts, fs, &searchError{Kind: unreadableFile, Path: path, Err: err})
+		return
+	}
+	enc, bomLen := fileEncoding(opts, head)
+	if enc != "utf-8" {
+		infov2(opts, "transcoding file from %v: '%v'", enc, path)
+		head = decodeBytes(head[bomLen:], enc)
+	}
+	binary := false
+	if opts.BinaryMode != "text" && isBinary(opts, head) {
+		if opts.BinaryMode == "skip" {
+			infov2(opts, "rejecting binary file: '%v'", path)
+			return
+		}
+		binary = true
+	}
+	if opts.SkipGenerated {
+		if kind, found := detectGenerated(head); found {
+			skipGenerated(opts, fs, path, kind)
+			return
+		}
+	}
 
 	// Create the AND tables for accept, delete and reject.
 	aa := []bool{}
@@ -175,7 +347,16 @@ func checkFile(opts cliOptions, path string, stat os.FileInfo, fs *findStats) {
 	// stateless but that the AND accept/reject are not.
 	// For the AND conditions we keep track of all of the unique
 	// matches.
-	lines := readLines(opts, path)
+	lr, err := newLineReader(ctx, opts, path, enc, bomLen)
+	if err != nil {
+		if searchStopped(ctx) {
+			abandonFile(ctx, opts, path, fs)
+			return
+		}
+		reportSearchError(opts, fs, &searchError{Kind: unreadableFile, Path: path, Err: err})
+		return
+	}
+	defer lr.Close()
 	matchedLines := []int{}
 	fileRejected := false
 	fileAllAndAccepted := false
@@ -184,11 +365,16 @@ func checkFile(opts cliOptions, path string, stat os.FileInfo, fs *findStats) {
 	var aa1 bool // accept any for an AND condition (partial match)
 	var da1 bool // delete accept any for an AND condition (partial match)
 
-	before := make([][]string, 0)
-	after := make([][]string, 0)
-	for i, line := range lines {
+	for i := 0; lr.Next(); i++ {
+		line := lr.Lines[i]
 		infov3(opts, "line: %04d %v : %v", i+1, path, line)
 
+		// The search may have been stopped or the file timed out.
+		if searchStopped(ctx) {
+			abandonFile(ctx, opts, path, fs)
+			return
+		}
+
 		// Check reject patterns.
 		ra1, _ := checkAndConditions(line, opts.RejectAndPatterns, &ra)
 		ro1 := checkOrConditions(line, opts.RejectOrPatterns)
@@ -234,165 +420,186 @@ func checkFile(opts cliOptions, path string, stat os.FileInfo, fs *findStats) {
 		infov3(opts, "   fileAllAndAccepted : %v", fileAllAndAccepted)
 
 		// Any partial matches are collected for later.
+		// The number of matched lines is limited by --max-count.
 		if aa1 == true || ao1 == true {
-			if len(line) > 0 {
+			if len(line) > 0 && (opts.MaxCount <= 0 || len(matchedLines) < opts.MaxCount) {
 				matchedLines = append(matchedLines, i)
+			}
+		}
 
-				// Before context.
-				if opts.Before > 0 {
-					var context []string
-					for k := i - opts.Before; k < i && k < len(lines); k++ {
-						if k >= 0 {
-							context = append(context, lines[k])
-						}
-					}
-					before = append(before, context)
-				}
-
-				// After context.
-				if opts.After > 0 {
-					var context []string
-					for k := i + 1; k < (i+opts.After+1) && k < len(lines); k++ {
-						if k >= 0 {
-							context = append(context, lines[k])
-						}
-					}
-					after = append(after, context)
-				}
-
+		// Stop reading as soon as the outcome cannot change. That is
+		// only possible if there are no reject patterns left to check.
+		// If no lines are reported, the first accept is enough unless
+		// the matched lines are counted for the summary, otherwise
+		// --max-count matched lines are needed.
+		if len(opts.RejectOrPatterns) == 0 && len(opts.RejectAndPatterns) == 0 && (fileAllAndAccepted == true || fileAnyOrAccepted) {
+			if (opts.Lines == NoLines && opts.Summary == false && opts.Verbose == 0) || opts.Quiet || binary {
+				break
+			}
+			if opts.MaxCount > 0 && len(matchedLines) >= opts.MaxCount {
+				break
 			}
 		}
 	}
+	if lr.Err() != nil {
+		if searchStopped(ctx) {
+			abandonFile(ctx, opts, path, fs)
+			return
+		}
+		reportSearchError(opts, fs, &searchError{Kind: scannerError, Path: path, Err: lr.Err()})
+	}
 
 	matched := false
 	if fileRejected == false && (fileAllAndAccepted == true || fileAnyOrAccepted) {
-		mutex.Lock()
-		matched = true
-		fs.FilesMatched++
-		fs.LinesMatched += int64(len(matchedLines))
-		if opts.Lines != RawLines {
-			// Do not print the file name for raw lines.
-			if opts.Colorize {
-				fmt.Printf("\033[1m%v\033[0m\n", path)
-			} else {
-				fmt.Printf("%v\n", path)
-			}
-		}
-		if opts.Lines != NoLines {
-			for m, i := range matchedLines {
-				lineno := i + 1
-				line := lines[i]
-
-				// Before
-				if opts.Before > 0 {
-					if opts.Colorize {
-						fmt.Printf("%8s \033[38;5;245m|----------------------------------------------------------------\033[0m\n", "")
-						for _, c := range before[m] {
-							fmt.Printf("\033[38;5;245m%8s |-%v\033[0m", "", c)
-							printNewline(c)
-						}
-					} else {
-						fmt.Printf("%8s |----------------------------------------------------------------\n", "")
-						for _, c := range before[m] {
-							fmt.Printf("%8s |-%v", "", c)
-							printNewline(c)
-						}
-					}
-				}
-
-				// Line.
-				if opts.Colorize {
-					if opts.Lines == DecoratedLines {
-						fmt.Printf("\033[38;5;245m%8d | \033[0m%v", lineno, colorizeLine(opts, line))
-					} else if opts.Lines == RawLines {
-						fmt.Printf("%v", colorizeLine(opts, line))
-					}
-					printNewline(line)
-				} else {
-					if opts.Lines == DecoratedLines {
-						fmt.Printf("%8d | %v", lineno, line)
-					} else if opts.Lines == RawLines {
-						fmt.Printf("%v", line)
-					}
-					printNewline(line)
+		// The file qualifies. By default the lines that qualified it are
+		// reported but --show and --invert-lines select them separately.
+		// Both need all of the lines as does --scope-block. Otherwise
+		// only the after context of the last matched line is needed.
+		if opts.Lines != NoLines && binary == false {
+			if opts.InvertLines || len(opts.ShowPatterns) > 0 || opts.ScopeBlock {
+				lr.ReadAll()
+			} else if n := len(matchedLines); n > 0 {
+				for len(lr.Lines) <= matchedLines[n-1]+opts.After && lr.Next() {
 				}
-
-				// After.
-				if opts.After > 0 {
-					if opts.Colorize {
-						for _, c := range after[m] {
-							fmt.Printf("\033[38;5;245m%8s |+%v\033[0m", "", c)
-							printNewline(c)
-						}
-						fmt.Printf("%8s \033[38;5;245m|++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++\033[0m\n", "")
-					} else {
-						for _, c := range after[m] {
-							fmt.Printf("%8s |+%v", "", c)
-							printNewline(c)
-						}
-						fmt.Printf("%8s |++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++\n", "")
-					}
+			}
+			if opts.InvertLines || len(opts.ShowPatterns) > 0 {
+				matchedLines = selectLines(opts, lr.Lines)
+				if opts.MaxCount > 0 && len(matchedLines) > opts.MaxCount {
+					matchedLines = matchedLines[:opts.MaxCount]
 				}
 			}
 		}
-		mutex.Unlock()
+		matched = reportMatches(ctx, opts, path, lr.Lines, matchedLines, binary, fs)
 	}
 
-	infov2(opts, "read %v lines, %v bytes, matched=%v", len(lines), stat.Size(), matched)
+	if matched && enc != "utf-8" {
+		infov(opts, "matched file was transcoded from %v: '%v'", enc, path)
+	}
+	infov2(opts, "read %v lines, %v bytes, matched=%v", len(lr.Lines), stat.Size(), matched)
 	return
 }
 
-// printNewline prints a new line if it is needed.
-func printNewline(line string) {
-	if len(line) == 0 {
-		fmt.Printf("\n")
-	} else if line[len(line)-1] != '\n' {
-		fmt.Printf("\n")
+// reportMatches updates the statistics and prints the matches of a file
+// unless the search has already been stopped. It enforces --max-total
+// and stops the search when it is reached or, for --quiet, on the first
+// match. It returns true if the file was reported.
+// The lines of a binary file are not printed for --binary=match.
+func reportMatches(ctx context.Context, opts cliOptions, path string, lines []string, matchedLines []int, binary bool, fs *findStats) bool {
+	mutex.Lock()
+	defer mutex.Unlock()
+	if searchStopped(ctx) {
+		return false
+	}
+	fs.FilesMatched++
+	if opts.Quiet {
+		cancelSearch(errLimitReached)
+		return true
 	}
+
+	// A match is a reported line or, if lines are not reported, a file.
+	if opts.MaxTotal > 0 {
+		if opts.Lines == NoLines {
+			fs.TotalMatches++
+		} else {
+			if remaining := opts.MaxTotal - fs.TotalMatches; int64(len(matchedLines)) > remaining {
+				matchedLines = matchedLines[:remaining]
+			}
+			fs.TotalMatches += int64(len(matchedLines))
+		}
+		if fs.TotalMatches >= opts.MaxTotal {
+			cancelSearch(errLimitReached)
+		}
+	}
+	fs.LinesMatched += int64(len(matchedLines))
+	if binary {
+		printBinaryMatch(opts, path)
+	} else {
+		printMatches(opts, path, lines, matchedLines)
+	}
+	return true
 }
 
-// colorizeLine colorizes a line.
-func colorizeLine(opts cliOptions, line string) string {
-	// At this point we know that we have a match.
-	// TODO: find the longest match.
-	// For now, just grab the first match.
-	// opts.AcceptAndPatterns
-	// opts.AcceptOrPatterns
-	if len(opts.AcceptOrPatterns) > 0 {
-		for _, re := range opts.AcceptOrPatterns {
-			line = re.ReplaceAllStringFunc(line, func(arg1 string) string {
-				return "\033[31;1m" + arg1 + "\033[0m"
-			})
+// selectLines returns the indexes of the lines to report for a file that
+// qualified.
+func selectLines(opts cliOptions, lines []string) (selected []int) {
+	for i, line := range lines {
+		if lineSelected(opts, line) {
+			selected = append(selected, i)
 		}
 	}
-	if len(opts.AcceptAndPatterns) > 0 {
-		for _, re := range opts.AcceptAndPatterns {
-			line = re.ReplaceAllStringFunc(line, func(arg1 string) string {
-				return "\033[31;1m" + arg1 + "\033[0m"
-			})
+	return
+}
+
+// lineSelected returns true if the line should be reported.
+// If --show patterns were specified, a line is selected if it matches any
+// of them. Otherwise it is selected if it matches any accept pattern and
+// it is not deleted. The result is inverted by --invert-lines.
+func lineSelected(opts cliOptions, line string) (selected bool) {
+	if len(opts.ShowPatterns) > 0 {
+		selected = checkOrConditions(line, opts.ShowPatterns)
+	} else if checkOrConditions(line, opts.AcceptOrPatterns) || checkOrConditions(line, opts.AcceptAndPatterns) {
+		selected = true
+		if checkOrConditions(line, opts.DeleteOrPatterns) {
+			selected = false
+		} else if len(opts.DeleteAndPatterns) > 0 && len(matchesAny(line, opts.DeleteAndPatterns)) == len(opts.DeleteAndPatterns) {
+			selected = false
 		}
 	}
-	return line
+	if opts.InvertLines {
+		selected = !selected
+	}
+	return
+}
+
+// lineReader reads the lines of a file on demand. The lines that have
+// been read are kept because they are needed for the context.
+type lineReader struct {
+	Lines   []string
+	file    io.ReadCloser
+	scanner *bufio.Scanner
 }
 
-// Read the lines from the file.
-func readLines(opts cliOptions, path string) (lines []string) {
-	file, err := os.Open(path)
+// newLineReader opens the file for reading. The byte order mark is
+// skipped and the lines are transcoded from the encoding to UTF-8.
+func newLineReader(ctx context.Context, opts cliOptions, path string, enc string, bomLen int) (*lineReader, error) {
+	file, err := openFile(ctx, path)
 	if err != nil {
-		warning(opts, "unable to open file: %v", err)
-		return
+		return nil, err
 	}
-	defer file.Close()
-	s := bufio.NewScanner(file)
+	if _, err := io.CopyN(io.Discard, file, int64(bomLen)); err != nil {
+		file.Close()
+		return nil, err
+	}
+	s := bufio.NewScanner(newDecodingReader(file, enc))
 	sbuf := make([]byte, opts.ScanBufInitSize)
 	s.Buffer(sbuf, opts.ScanBufMaxSize)
-	for s.Scan() {
-		lines = append(lines, s.Text())
+	return &lineReader{file: file, scanner: s}, nil
+}
+
+// Next reads the next line. It returns false at the end of the file or
+// if there was an error.
+func (lr *lineReader) Next() bool {
+	if lr.scanner.Scan() == false {
+		return false
 	}
-	if err := s.Err(); err != nil {
-		warning(opts, "scanner error: %v", err)
+	lr.Lines = append(lr.Lines, lr.scanner.Text())
+	return true
+}
+
+// ReadAll reads the rest of the lines.
+func (lr *lineReader) ReadAll() {
+	for lr.Next() {
 	}
-	return
+}
+
+// Err returns the first error that was encountered.
+func (lr *lineReader) Err() error {
+	return lr.scanner.Err()
+}
+
+// Close closes the file.
+func (lr *lineReader) Close() {
+	lr.file.Close()
 }
 
 // checkOrCondition, return True if anything matches.
@@ -437,17 +644,40 @@ func checkAndConditions(data string, ps []*regexp.Regexp, array *[]bool) (matche
 
 // matchesFileName test whether a file name matches all of the related
 // criteria.
-func matchFileName(opts cliOptions, path string) (match bool) {
+func matchFileName(opts cliOptions, root string, path string) (match bool) {
 	var p *regexp.Regexp
 
+	// The name patterns match the joined path unless --match-relative
+	// was specified.
+	name := path
+	if opts.MatchRelative {
+		name = relativePath(root, path)
+	}
+
 	// Exclude rules have priority.
 	// If a valid exclude is found, it overrides the include rules.
 	match = false
 
+	// The --type-not types are excluded like the exclude OR patterns.
+	if len(opts.TypesNot) > 0 && matchFileType(opts.TypesNot, path) {
+		return
+	}
+
+	// The globs are matched against the path relative to the root.
+	// The negated globs are excluded like the exclude OR patterns.
+	globMatched := false
+	if len(opts.Globs) > 0 {
+		var globNegated bool
+		globMatched, globNegated = matchGlobs(opts.Globs, root, path)
+		if globNegated {
+			return
+		}
+	}
+
 	// Any of the exclude OR patterns must match to exclude this file.
 	if len(opts.ExcludeOrPatterns) > 0 {
 		for _, p := range opts.ExcludeOrPatterns {
-			if p.MatchString(path) == true {
+			if p.MatchString(name) == true {
 				return
 			}
 		}
@@ -457,7 +687,7 @@ func matchFileName(opts cliOptions, path string) (match bool) {
 	if len(opts.ExcludeAndPatterns) > 0 {
 		all := true
 		for _, p = range opts.ExcludeAndPatterns {
-			if p.MatchString(path) == false {
+			if p.MatchString(name) == false {
 				all = false
 				break
 			}
@@ -472,10 +702,19 @@ func matchFileName(opts cliOptions, path string) (match bool) {
 	// but we need to check for explicit includes.
 	match = true
 
+	// The --type types and the globs are included like the include OR
+	// patterns.
+	if len(opts.Types) > 0 && matchFileType(opts.Types, path) {
+		return
+	}
+	if globMatched {
+		return
+	}
+
 	// Any of the include OR patterns must match to include this file.
 	if len(opts.IncludeOrPatterns) > 0 {
 		for _, p = range opts.IncludeOrPatterns {
-			if p.MatchString(path) == true {
+			if p.MatchString(name) == true {
 				return
 			}
 		}
@@ -485,7 +724,7 @@ func matchFileName(opts cliOptions, path string) (match bool) {
 	if len(opts.IncludeAndPatterns) > 0 {
 		all := true
 		for _, p = range opts.IncludeAndPatterns {
-			if p.MatchString(path) == false {
+			if p.MatchString(name) == false {
 				all = false
 				break
 			}
@@ -500,7 +739,7 @@ func matchFileName(opts cliOptions, path string) (match bool) {
 	// If only include patterns were defined, then exclude this file.
 	// If only exclude patterns were defined, then include this file.
 	// If both were defined, reject the file.
-	if len(opts.IncludeAndPatterns) > 0 || len(opts.IncludeOrPatterns) > 0 {
+	if len(opts.IncludeAndPatterns) > 0 || len(opts.IncludeOrPatterns) > 0 || len(opts.Types) > 0 || hasPositiveGlobs(opts.Globs) {
 		// Include patterns specified, never match.
 		match = false
 	} else {
@@ -541,63 +780,41 @@ func matchesAny(data string, patterns []*regexp.Regexp) (indexes []int) {
 	return
 }
 
-// isBinary determines whether a file is binary.
-// It is a bit of a hack.
-func isBinary(opts cliOptions, path string, stat os.FileInfo) bool {
-	file, err := os.Open(path)
+// readHead reads the first size bytes of a file for the binary and the
+// generated checks. It is shorter if the file is.
+// An error is returned if the file cannot be read.
+func readHead(ctx context.Context, path string, size int) ([]byte, error) {
+	file, err := openFile(ctx, path)
 	if err != nil {
-		infov2(opts, "unable to open file: %v", err)
-		return true // skip files that we cannot open
+		return nil, err
 	}
 	defer file.Close()
-
-	// Read the first N bytes.
-	// If the file is smaller than the specified binary test size, then use that
-	// size instead.
-	size := int64(opts.BinarySize)
-	if size > stat.Size() {
-		size = stat.Size()
-	}
-	var buf = make([]byte, size)
-	_, err = file.Read(buf)
-	if err != nil {
-		warning(opts, "binary test: %v - %v", path, err)
-		return true // skip files with read errors
+	buf := make([]byte, size)
+	n, err := io.ReadFull(file, buf)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, err
 	}
-
-	n := 0 // number of new lines
-	for i, b := range buf {
-		switch b {
-		case 0:
-			// The last byte (EOF) is always NULL.
-			if int64(i) < stat.Size() {
-				return true
-			}
-		case '\n':
-			n++
-		}
-	}
-
-	// The first N bytes must contain at least 1 newline and no NULLs.
-	if n == 0 {
-		return true
-	}
-
-	// Passed all of the binary tests, assume that it is a text file.
-	return false
+	return buf[:n], nil
 }
 
 // validTimestamp returns true if the file is in range.
+// The time that is checked is selected by --time-field. Like find's
+// -newer, the file must be strictly newer than the --newer-than-file
+// reference.
 func validTimestamp(opts cliOptions, path string, stat os.FileInfo) bool {
+	t := fileTime(opts, stat)
+	if len(opts.NewerThanFile) > 0 && t.After(opts.NewerThanFileTime) == false {
+		return false
+	}
 	if opts.NewerThanFlag == true {
-		f := stat.ModTime().After(opts.NewerThan) || stat.ModTime().Equal(opts.NewerThan)
+		f := t.After(opts.NewerThan) || t.Equal(opts.NewerThan)
 		// Don't exit if it is true, there may be an older-than check.
 		if f == false {
 			return f
 		}
 	}
 	if opts.OlderThanFlag == true {
-		return stat.ModTime().Before(opts.OlderThan) || stat.ModTime().Equal(opts.OlderThan)
+		return t.Before(opts.OlderThan) || t.Equal(opts.OlderThan)
 	}
 	return true // no timestamp is a valid timestamp
 }
@@ -72,8 +72,6 @@ func warning(opts cliOptions, f string, a ...interface{}) {
 	}
 }
 
-func fatal(f string, a ...interface{}) {
-	_, _, lineno, _ := runtime.Caller(1)
-	msg := fmt.Sprintf(f, a...)
-	log.Fatalf("FATAL   %5d - %v\n", lineno, msg)
+func errorMsg(f string, a ...interface{}) {
+	_msg("ERROR", f, a...)
 }
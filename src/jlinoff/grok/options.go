@@ -2,7 +2,6 @@
 package main
 
 import (
-	"bufio"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -22,10 +21,11 @@ type cliDatetime struct {
 
 // Line reporting.
 type LineReportingType int
+
 const (
-	NoLines LineReportingType = iota + 1 // No lines are reported
-	DecoratedLines	// Lines have a line number prefix, file names are reported.
-	RawLines // Just the lines are reported.
+	NoLines        LineReportingType = iota + 1 // No lines are reported
+	DecoratedLines                              // Lines have a line number prefix, file names are reported.
+	RawLines                                    // Just the lines are reported.
 )
 
 // command line options
@@ -34,54 +34,119 @@ type cliOptions struct {
 	AcceptOrPatterns   []*regexp.Regexp // -a
 	After              int              // -x after
 	Before             int              // -y before
-	Binary             bool             // -b
+	BinaryMode         string           // -b, --binary=MODE
 	BinarySize         int              // -B
 	CmdLine            string
-	Colorize           bool // --color
-	Dirs               []string
+	ColorMode          string // --color=WHEN
+	Colorize           bool
+	Colors             colorScheme      // GROK_COLORS
+	Column             bool             // --column, --vimgrep
 	DeleteAndPatterns  []*regexp.Regexp // -D
 	DeleteOrPatterns   []*regexp.Regexp // -d
-	ExcludeAndPatterns []*regexp.Regexp // -E
-	ExcludeOrPatterns  []*regexp.Regexp // -i
-	IncludeAndPatterns []*regexp.Regexp // -I
-	IncludeOrPatterns  []*regexp.Regexp // -i
+	DirConf            bool             // --no-config
+	DirIncludePatterns []*regexp.Regexp // --dir-include
+	Dirs               []string
+	Encoding           string            // --encoding
+	ExcludeAndPatterns []*regexp.Regexp  // -E
+	ExcludeOrPatterns  []*regexp.Regexp  // -e
+	FileTimeout        time.Duration     // --file-timeout
+	Globs              []*globPattern    // -g
+	Heading            bool              // --heading, --no-heading, --vimgrep
+	Hidden             bool              // --hidden, --no-hidden
+	Hyperlink          bool              // --hyperlink
+	IncludeAndPatterns []*regexp.Regexp  // -I
+	IncludeOrPatterns  []*regexp.Regexp  // -i
+	InvertLines        bool              // --invert-lines
 	Lines              LineReportingType // -l, -L
-	MaxDepth           int              // -m
-	MaxJobs            int              // -M
-	NewerThan          time.Time        // -n
+	MatchRelative      bool              // --match-relative
+	MaxCount           int               // --max-count
+	MaxDepth           int               // -m
+	MaxJobs            int               // -M
+	MaxTotal           int64             // --max-total
+	MinDepth           int               // --min-depth
+	NewerThan          time.Time         // -n
 	NewerThanFlag      bool
+	NewerThanFile      string // --newer-than-file
+	NewerThanFileTime  time.Time
 	OlderThan          time.Time //-o
 	OlderThanFlag      bool
+	PathStyle          string           // --path-style
+	PruneAndPatterns   []*regexp.Regexp // --Prune
+	PruneGlobs         []*globPattern   // --prune-glob
+	PruneMarkers       []string         // --prune-marker
 	PruneOrPatterns    []*regexp.Regexp // -p
+	Quiet              bool             // -q
 	RejectAndPatterns  []*regexp.Regexp // -R
 	RejectOrPatterns   []*regexp.Regexp // -r
 	ScanBufInitSize    int              // -S, --scan-buf-params
 	ScanBufMaxSize     int              // -S, --scan-buf-params
+	ScopeAuto          bool             // --scope-auto
+	ScopeBlock         bool             // --scope-block
+	ScopePatterns      []*regexp.Regexp // --scope
+	ShowPatterns       []*regexp.Regexp // --show
+	SkipGenerated      bool             // --skip-generated
+	StripPrefix        string           // --strip-prefix
 	Summary            bool             // -s
+	TimeField          string           // --time-field
+	Timeout            time.Duration    // --timeout
+	Types              []string         // --type
+	TypesNot           []string         // --type-not
 	Verbose            int              // -v
 	Warnings           bool             // --no-warnings
+	WarningsAsErrors   bool             // --warnings-as-errors
+	WorkDir            string           // used by --path-style=relative
+	Zip                string           // --zip
 }
 
-func loadCliOptions() (opts cliOptions) {
+// loadCliOptions loads the options from the command line. An
+// *optionError is returned if an option is not valid.
+func loadCliOptions() (opts cliOptions, err error) {
 	opts.Verbose = 0
 	opts.MaxDepth = -1 // all files
 	opts.BinarySize = 1024
+	opts.BinaryMode = "skip"
+	opts.Encoding = "auto"
 	opts.CmdLine = cliCmdLine()
 	opts.Warnings = true
+	opts.TimeField = "mtime"
 	opts.MaxJobs = runtime.NumCPU()
 	opts.ScanBufInitSize = 1024 * 1024
 	opts.ScanBufMaxSize = 10 * opts.ScanBufInitSize
 	opts.Lines = NoLines
+	opts.Heading = true
+	opts.ColorMode = "auto"
+	opts.PathStyle = "as-given"
+	opts.DirConf = true
+	opts.PruneMarkers = []string{"CACHEDIR.TAG", ".grokskip"}
 
 	// Used to detect nested conf files.
 	confMap := map[string]string{}
 
+	// The arguments from the config files and GROK_OPTS precede the
+	// command line arguments so that the command line has the last
+	// word. The origin of each argument is tracked for the verbose
+	// trace and for error messages.
+	confArgs, confOrigins, confTrace, err := loadConfigArgs(os.Args[1:])
+	if err != nil {
+		return
+	}
+
 	// Make a local copy of the arguments because we have to modify
 	// it.
 	cache := make([]string, 0)
-	args := append([]string{}, os.Args...)
+	args := append([]string{os.Args[0]}, confArgs...)
+	args = append(args, os.Args[1:]...)
+	origins := append([]string{""}, confOrigins...)
+	for len(origins) < len(args) {
+		origins = append(origins, "command line")
+	}
+	trace := []string{}
+	appliedProfiles := map[string]bool{}
+	listProfilesFlag := false
+	typeListFlag := false
 	for i := 1; i < len(args) || len(cache) != 0; i++ {
 		var arg string
+		inline := false // the argument was specified as --name=value
 		if len(cache) > 0 {
 			// There are still single arguments in the cache,
 			// handle them first.
@@ -99,159 +164,396 @@ func loadCliOptions() (opts cliOptions) {
 					cache = append(cache, newArg)
 				}
 				arg = arg[:2] // grab the first option
+			} else if k := strings.Index(arg, "="); k > 2 && strings.HasPrefix(arg, "--") {
+				// Split --name=value into two arguments.
+				// This allows specifications like:
+				//   --color=never
+				args = append(args[:i+1], append([]string{arg[k+1:]}, args[i+1:]...)...)
+				origins = append(origins[:i+1], append([]string{origins[i]}, origins[i+1:]...)...)
+				arg = arg[:k]
+				inline = true
 			}
 		}
+		j := i // the values of the option are args[j+1:i+1]
 		switch arg {
 		case "-a", "--accept":
-			opts.AcceptOrPatterns = append(opts.AcceptOrPatterns, cliGetNextArgRegexp(&i, args))
+			opts.AcceptOrPatterns = append(opts.AcceptOrPatterns, cliGetNextArgRegexp(&i, args, &err))
 		case "-A", "--Accept", "--ACCEPT":
-			opts.AcceptAndPatterns = append(opts.AcceptAndPatterns, cliGetNextArgRegexp(&i, args))
+			opts.AcceptAndPatterns = append(opts.AcceptAndPatterns, cliGetNextArgRegexp(&i, args, &err))
 		case "-z", "--after":
-			opts.After = cliGetNextArgInt(&i, args)
+			opts.After = cliGetNextArgCount(&i, args, &err)
 		case "-y", "--before":
-			opts.Before = cliGetNextArgInt(&i, args)
+			opts.Before = cliGetNextArgCount(&i, args, &err)
 		case "-b", "--binary":
-			opts.Binary = true
+			// The MODE argument is optional so it must be specified
+			// inline: --binary=MODE.
+			opts.BinaryMode = "text"
+			if inline {
+				opts.BinaryMode = cliGetNextArgChoice(&i, args, binaryModes, &err)
+			}
 		case "-B", "--binary-size":
-			opts.BinarySize = cliGetNextArgInt(&i, args)
+			opts.BinarySize = cliGetNextArgCount(&i, args, &err)
 		case "-c", "--conf":
-			readOptsConfFile(&i, &args, confMap)
+			err = readOptsConfFile(&i, &args, &origins, confMap)
 		case "-C", "--color", "--colorize":
-			opts.Colorize = true
+			// The WHEN argument is optional so it must be specified
+			// inline: --color=WHEN.
+			opts.ColorMode = "always"
+			if inline {
+				opts.ColorMode = cliGetNextArgChoice(&i, args, []string{"auto", "always", "never"}, &err)
+			}
+		case "--column":
+			opts.Column = true
+		case "--list-profiles":
+			listProfilesFlag = true
+		case "--no-config":
+			// The config files are handled by loadConfigArgs.
+			opts.DirConf = false
 		case "-d", "--delete":
-			opts.DeleteOrPatterns = append(opts.DeleteOrPatterns, cliGetNextArgRegexp(&i, args))
+			opts.DeleteOrPatterns = append(opts.DeleteOrPatterns, cliGetNextArgRegexp(&i, args, &err))
 		case "-D", "--Delete", "--DELETE":
-			opts.DeleteAndPatterns = append(opts.DeleteAndPatterns, cliGetNextArgRegexp(&i, args))
+			opts.DeleteAndPatterns = append(opts.DeleteAndPatterns, cliGetNextArgRegexp(&i, args, &err))
+		case "--dir-include":
+			opts.DirIncludePatterns = append(opts.DirIncludePatterns, cliGetNextArgRegexp(&i, args, &err))
+		case "--encoding":
+			opts.Encoding = cliGetNextArgChoice(&i, args, encodings, &err)
 		case "-e", "--exclude":
-			opts.ExcludeOrPatterns = append(opts.ExcludeOrPatterns, cliGetNextArgRegexp(&i, args))
+			opts.ExcludeOrPatterns = append(opts.ExcludeOrPatterns, cliGetNextArgRegexp(&i, args, &err))
 		case "-E", "--Exclude", "--EXCLUDE":
-			opts.ExcludeAndPatterns = append(opts.ExcludeAndPatterns, cliGetNextArgRegexp(&i, args))
+			opts.ExcludeAndPatterns = append(opts.ExcludeAndPatterns, cliGetNextArgRegexp(&i, args, &err))
+		case "--file-timeout":
+			opts.FileTimeout = cliGetNextArgDuration(&i, args, &err)
+		case "-g", "--glob":
+			opts.Globs = append(opts.Globs, cliGetNextArgGlob(&i, args, &err))
 		case "-h", "--help":
 			help()
+		case "--heading":
+			opts.Heading = true
+		case "--no-heading":
+			opts.Heading = false
+		case "--hidden":
+			opts.Hidden = true
+		case "--no-hidden":
+			opts.Hidden = false
+		case "--hyperlink":
+			opts.Hyperlink = true
 		case "-i", "--include":
-			opts.IncludeOrPatterns = append(opts.IncludeOrPatterns, cliGetNextArgRegexp(&i, args))
+			opts.IncludeOrPatterns = append(opts.IncludeOrPatterns, cliGetNextArgRegexp(&i, args, &err))
 		case "-I", "--Include", "--INCLUDE":
-			opts.IncludeAndPatterns = append(opts.IncludeAndPatterns, cliGetNextArgRegexp(&i, args))
+			opts.IncludeAndPatterns = append(opts.IncludeAndPatterns, cliGetNextArgRegexp(&i, args, &err))
+		case "--invert-lines":
+			opts.InvertLines = true
 		case "-l", "--lines":
 			opts.Lines = DecoratedLines
 		case "-L", "--Lines", "--LINES":
 			opts.Lines = RawLines
 		case "-m", "--max-depth":
-			opts.MaxDepth = cliGetNextArgInt(&i, args)
+			opts.MaxDepth = cliGetNextArgInt(&i, args, &err)
+		case "--match-relative":
+			opts.MatchRelative = true
+		case "--max-count":
+			opts.MaxCount = cliGetNextArgInt(&i, args, &err)
 		case "-M", "--max-jobs":
-			opts.MaxJobs = cliGetNextArgInt(&i, args)
+			opts.MaxJobs = cliGetNextArgInt(&i, args, &err)
 			if opts.MaxJobs < 1 {
 				opts.MaxJobs = 1
 			}
+		case "--max-total":
+			opts.MaxTotal = int64(cliGetNextArgInt(&i, args, &err))
+		case "--min-depth":
+			opts.MinDepth = cliGetNextArgInt(&i, args, &err)
 		case "-n", "--newer-than":
 			opts.NewerThanFlag = true
-			opts.NewerThan = cliGetNextArgDatetime(&i, args)
-		case "-o", "--olderthan-than":
+			opts.NewerThan = cliGetNextArgDatetime(&i, args, &err)
+		case "--newer-than-file":
+			opts.NewerThanFile = cliGetNextArg(&i, args, &err)
+		case "-o", "--older-than", "--olderthan-than":
 			opts.OlderThanFlag = true
-			opts.OlderThan = cliGetNextArgDatetime(&i, args)
+			opts.OlderThan = cliGetNextArgDatetime(&i, args, &err)
+		case "--path-style":
+			opts.PathStyle = cliGetNextArgChoice(&i, args, []string{"as-given", "relative", "absolute", "basename"}, &err)
+		case "--prune-glob":
+			opts.PruneGlobs = append(opts.PruneGlobs, cliGetNextArgGlob(&i, args, &err))
+		case "--prune-marker":
+			opts.PruneMarkers = append(opts.PruneMarkers, cliGetNextArgMarker(&i, args, &err))
+		case "--no-prune-markers":
+			opts.PruneMarkers = []string{}
+		case "-P", "--profile":
+			err = applyProfile(&i, &args, &origins, appliedProfiles)
 		case "-p", "--prune":
-			opts.PruneOrPatterns = append(opts.PruneOrPatterns, cliGetNextArgRegexp(&i, args))
+			opts.PruneOrPatterns = append(opts.PruneOrPatterns, cliGetNextArgRegexp(&i, args, &err))
+		case "--Prune", "--PRUNE":
+			opts.PruneAndPatterns = append(opts.PruneAndPatterns, cliGetNextArgRegexp(&i, args, &err))
+		case "-q", "--quiet":
+			opts.Quiet = true
 		case "-r", "--reject":
-			opts.RejectOrPatterns = append(opts.RejectOrPatterns, cliGetNextArgRegexp(&i, args))
+			opts.RejectOrPatterns = append(opts.RejectOrPatterns, cliGetNextArgRegexp(&i, args, &err))
 		case "-R", "--Reject", "--REJECT":
-			opts.RejectAndPatterns = append(opts.RejectAndPatterns, cliGetNextArgRegexp(&i, args))
+			opts.RejectAndPatterns = append(opts.RejectAndPatterns, cliGetNextArgRegexp(&i, args, &err))
+		case "--show":
+			opts.ShowPatterns = append(opts.ShowPatterns, cliGetNextArgRegexp(&i, args, &err))
+		case "--skip-generated":
+			opts.SkipGenerated = true
 		case "-s", "--summary":
 			opts.Summary = true
 		case "-S", "--scan-buf-params":
-			opts.ScanBufInitSize = cliGetNextArgInt(&i, args)
-			opts.ScanBufMaxSize = cliGetNextArgInt(&i, args)
+			opts.ScanBufInitSize = cliGetNextArgInt(&i, args, &err)
+			opts.ScanBufMaxSize = cliGetNextArgInt(&i, args, &err)
+		case "--scope":
+			opts.ScopePatterns = append(opts.ScopePatterns, cliGetNextArgRegexp(&i, args, &err))
+		case "--scope-auto":
+			opts.ScopeAuto = true
+		case "--scope-block":
+			opts.ScopeBlock = true
+		case "--time-field":
+			opts.TimeField = cliGetNextArgChoice(&i, args, timeFields, &err)
+		case "--timeout":
+			opts.Timeout = cliGetNextArgDuration(&i, args, &err)
+		case "--strip-prefix":
+			opts.StripPrefix = cliGetNextArg(&i, args, &err)
+		case "-t", "--type":
+			opts.Types = append(opts.Types, cliGetNextArg(&i, args, &err))
+		case "-T", "--type-not":
+			opts.TypesNot = append(opts.TypesNot, cliGetNextArg(&i, args, &err))
+		case "--type-add":
+			def := cliGetNextArg(&i, args, &err)
+			if err == nil {
+				if terr := parseTypeAdd(def); terr != nil {
+					err = optionErrorf("invalid value for --type-add: '%v', %v", def, terr)
+				}
+			}
+		case "--type-list":
+			typeListFlag = true
 		case "-v", "--verbose":
 			opts.Verbose++
 		case "-vv", "-vvv", "-vvvv":
 			opts.Verbose += len(arg) - 1
+		case "--vimgrep":
+			opts.Heading = false
+			opts.Column = true
+			opts.Lines = DecoratedLines
 		case "-V", "--version":
 			fmt.Printf("%v version %v\n", filepath.Base(os.Args[0]), version)
 			os.Exit(0)
 		case "-W", "--no-warnings":
 			opts.Warnings = false
+		case "--warnings-as-errors":
+			opts.WarningsAsErrors = true
+		case "--zip":
+			opts.Zip = cliGetNextArg(&i, args, &err)
 		default:
 			// Everything that is not an option must be a valid directory or file.
-			_, err := os.Stat(arg)
-			if err == nil {
-				opts.Dirs = append(opts.Dirs, arg)
+			// They are checked after --zip is known.
+			// If there is a leading "-" and it does not exist, assume that the
+			// user specified an invalid option.
+			if _, serr := os.Stat(arg); serr != nil && strings.HasPrefix(arg, "-") {
+				err = optionErrorf("unrecognized option '%v'", arg)
 			} else {
-				// The directory/file does not exist.
-				// If there is a leading "-", assume that the user specified an invalid
-				// option.
-				if strings.HasPrefix(arg, "-") {
-					fatal("unrecognized option '%v'", arg)
-				}
-				fatal("%v", err)
+				opts.Dirs = append(opts.Dirs, arg)
 			}
 		}
+		if err != nil {
+			if origins[j] != "command line" {
+				err = optionErrorf("%v: %v", origins[j], err)
+			}
+			return
+		}
+		trace = append(trace, fmt.Sprintf("%-24v %v", strings.Join(append([]string{arg}, args[j+1:i+1]...), " "), origins[j]))
+	}
+
+	// All of the conf files have been read so all of the profiles are
+	// known.
+	if listProfilesFlag {
+		listProfiles(os.Stdout)
+		os.Exit(0)
+	}
+
+	// The types are checked after all of the --type-add definitions.
+	if typeListFlag {
+		listFileTypes(os.Stdout)
+		os.Exit(0)
+	}
+	if err = checkFileTypes(append(opts.Types, opts.TypesNot...)); err != nil {
+		return
+	}
+
+	// Show where the options came from.
+	for _, t := range confTrace {
+		infov(opts, "config: %v", t)
+	}
+	for _, t := range trace {
+		infov2(opts, "option: %v", t)
+	}
+
+	// The reference file is compared using the same time field so it
+	// can only be checked after all of the options are known.
+	if len(opts.NewerThanFile) > 0 {
+		stat, serr := os.Stat(opts.NewerThanFile)
+		if serr != nil {
+			return opts, optionErrorf("invalid reference file for --newer-than-file: %v", serr)
+		}
+		opts.NewerThanFileTime = fileTime(opts, stat)
 	}
 
+	// The directories and files are in the archive for --zip.
+	if len(opts.Zip) > 0 {
+		if fileSystem, err = openZipFS(opts.Zip); err != nil {
+			return opts, optionErrorf("invalid archive for --zip: %v", err)
+		}
+	}
 	if len(opts.Dirs) == 0 {
 		opts.Dirs = append(opts.Dirs, ".")
 	}
+	for _, dir := range opts.Dirs {
+		if _, serr := fileSystem.Stat(dir); serr != nil {
+			return opts, optionErrorf("%v", serr)
+		}
+	}
+	if opts.PathStyle == "relative" {
+		if opts.WorkDir, err = os.Getwd(); err != nil {
+			return opts, optionErrorf("--path-style=relative: %v", err)
+		}
+	}
+
+	// The colors are only loaded when they are needed. If colorizing is
+	// disabled, the empty color scheme leaves the text unchanged.
+	opts.Colorize = useColor(opts.ColorMode)
+	if opts.Colorize {
+		opts.Colors, err = loadColorScheme()
+	}
+
+	// Without a heading the file name is printed on each line so the
+	// lines must be reported.
+	if opts.Heading == false && opts.Lines == NoLines {
+		opts.Lines = DecoratedLines
+	}
 	return
 }
 
 // cliGetNextArgDatetime
-func cliGetNextArgDatetime(i *int, args []string) time.Time {
+func cliGetNextArgDatetime(i *int, args []string, perr *error) time.Time {
 	j := *i
 	now := time.Now()
-	arg := cliGetNextArg(i, args)
-
-	// Map of valid arguments.
-	// This could easily be simple static structure but since this is a rare
-	// operation, this approach is okay.
-	m := map[string]cliDatetime{}
-	m["default"] = cliDatetime{Re: regexp.MustCompile("^([0-9]+)$"), Scale: time.Second}
-	m["second"] = cliDatetime{Re: regexp.MustCompile("^([0-9]+)[s]$"), Scale: time.Second}
-	m["minute"] = cliDatetime{Re: regexp.MustCompile("^([0-9]+)[m]$"), Scale: time.Minute}
-	m["hour"] = cliDatetime{Re: regexp.MustCompile("^([0-9]+)[h]$"), Scale: time.Minute}
-	m["day"] = cliDatetime{Re: regexp.MustCompile("^([0-9]+)[d]$"), Scale: time.Hour * time.Duration(24)}
-	m["week"] = cliDatetime{Re: regexp.MustCompile("^([0-9]+)[w]$"), Scale: time.Hour * time.Duration(24) * time.Duration(7)}
-
-	for _, rec := range m {
-		result := rec.Re.FindStringSubmatch(arg)
-		if len(result) > 1 {
-			val, _ := strconv.Atoi(result[1])
-			td := time.Duration(val) * rec.Scale
-			stamp := now.Add(-td)
-			return stamp
-		}
+	arg := cliGetNextArg(i, args, perr)
+	if *perr != nil {
+		return now
 	}
+	t, err := parseDatetime(arg, now)
+	if err != nil {
+		*perr = optionErrorf("invalid date format for %v: '%v', %v", args[j], arg, err)
+	}
+	return t
+}
 
-	// Not found.
-	fatal("invalid date format for %v: '%v'", args[j], arg)
-	return now
+// cliGetNextArgDuration
+func cliGetNextArgDuration(i *int, args []string, perr *error) time.Duration {
+	j := *i
+	arg := cliGetNextArg(i, args, perr)
+	if *perr != nil {
+		return 0
+	}
+	d, err := time.ParseDuration(arg)
+	if err != nil || d < 0 {
+		*perr = optionErrorf("invalid duration for %v: '%v', expected values like 500ms, 30s or 2m", args[j], arg)
+	}
+	return d
 }
 
 // cliGetNextArgRegexp
-func cliGetNextArgRegexp(i *int, args []string) *regexp.Regexp {
+func cliGetNextArgRegexp(i *int, args []string, perr *error) *regexp.Regexp {
 	j := *i
-	arg := cliGetNextArg(i, args)
+	arg := cliGetNextArg(i, args, perr)
+	if *perr != nil {
+		return nil
+	}
 	re, err := regexp.Compile(arg)
 	if err != nil {
-		fatal("could not compile regexp for %v: %v", args[j], err)
+		*perr = optionErrorf("could not compile regexp for %v: %v", args[j], err)
 	}
 	return re
 }
 
+// cliGetNextArgGlob
+func cliGetNextArgGlob(i *int, args []string, perr *error) *globPattern {
+	j := *i
+	arg := cliGetNextArg(i, args, perr)
+	if *perr != nil {
+		return nil
+	}
+	g, err := compileGlob(arg)
+	if err != nil {
+		*perr = optionErrorf("invalid glob for %v: %v", args[j], err)
+	}
+	return g
+}
+
+// cliGetNextArgMarker gets a marker file name. It cannot be a path
+// because it is looked up in the directory entries.
+func cliGetNextArgMarker(i *int, args []string, perr *error) string {
+	j := *i
+	arg := cliGetNextArg(i, args, perr)
+	if *perr != nil {
+		return ""
+	}
+	if len(arg) == 0 || arg == "." || arg == ".." || strings.ContainsAny(arg, "/"+string(filepath.Separator)) {
+		*perr = optionErrorf("invalid file name for %v: '%v'", args[j], arg)
+	}
+	return arg
+}
+
 // cliGetNextArgInt
-func cliGetNextArgInt(i *int, args []string) int {
+func cliGetNextArgInt(i *int, args []string, perr *error) int {
 	j := *i
-	arg := cliGetNextArg(i, args)
+	arg := cliGetNextArg(i, args, perr)
+	if *perr != nil {
+		return 0
+	}
 	val, err := strconv.Atoi(arg)
 	if err != nil {
-		fatal("not an integer for %v: %v", args[j], arg)
+		*perr = optionErrorf("not an integer for %v: %v", args[j], arg)
 	}
 	return val
 }
 
+// cliGetNextArgCount gets the next argument as an integer that cannot
+// be negative like a number of lines.
+func cliGetNextArgCount(i *int, args []string, perr *error) int {
+	j := *i
+	val := cliGetNextArgInt(i, args, perr)
+	if *perr == nil && val < 0 {
+		*perr = optionErrorf("negative value for %v: %v", args[j], val)
+	}
+	return val
+}
+
+// cliGetNextArgChoice
+func cliGetNextArgChoice(i *int, args []string, choices []string, perr *error) string {
+	j := *i
+	arg := cliGetNextArg(i, args, perr)
+	if *perr != nil {
+		return ""
+	}
+	for _, c := range choices {
+		if arg == c {
+			return arg
+		}
+	}
+	*perr = optionErrorf("invalid value for %v: '%v', expected one of: %v", args[j], arg, strings.Join(choices, ", "))
+	return arg
+}
+
 // cliGetNextArg gets the next command line argument.
-func cliGetNextArg(i *int, args []string) string {
+// The cliGetNextArg functions report errors through perr. If it is
+// already set, the argument is skipped so that the first error is kept.
+func cliGetNextArg(i *int, args []string, perr *error) string {
 	j := *i
 	*i++
+	if *perr != nil {
+		return ""
+	}
 	if *i >= len(args) {
-		fatal("missing argument for option %v", args[j])
+		*perr = optionErrorf("missing argument for option %v", args[j])
+		return ""
 	}
 	return args[*i]
 }
@@ -270,6 +572,30 @@ func quote(arg string) (result string) {
 	return
 }
 
+// applyProfile expands the profile and inserts its arguments into the
+// args array. A profile can only be applied once.
+func applyProfile(i *int, args *[]string, origins *[]string, applied map[string]bool) (err error) {
+	name := cliGetNextArg(i, *args, &err) // profile name
+	if err != nil {
+		return
+	}
+	if applied[name] {
+		return optionErrorf("profile '%v' is applied more than once", name)
+	}
+	applied[name] = true
+	newargs, neworigins, err := expandProfile(name, map[string]bool{})
+	if err != nil {
+		return
+	}
+
+	// Update the slices by reference.
+	if len(newargs) > 0 {
+		*args = append((*args)[:*i+1], append(newargs, (*args)[*i+1:]...)...)
+		*origins = append((*origins)[:*i+1], append(neworigins, (*origins)[*i+1:]...)...)
+	}
+	return nil
+}
+
 // get the command line
 func cliCmdLine() (cli string) {
 	cli = os.Args[0]
@@ -282,68 +608,36 @@ func cliCmdLine() (cli string) {
 }
 
 // getCanonicalPath
-func getCanonicalPath(path string) string {
+func getCanonicalPath(path string) (string, error) {
 	s, e := filepath.EvalSymlinks(path)
 	if e != nil {
-		fatal("%v", e)
+		return "", e
 	}
-	a, e := filepath.Abs(s)
-	if e != nil {
-		fatal("%v", e)
-	}
-	return a
+	return filepath.Abs(s)
 }
 
 // readOptsConfFile - reads the options configuration file and inserts them
 // into the args array.
-func readOptsConfFile(i *int, args *[]string, confMap map[string]string) {
-	conf := cliGetNextArg(i, *args) // conf file path
-	newargs := []string{}
-	path := getCanonicalPath(conf)
-	if _, found := confMap[path]; found {
-		// It was found! This could be an infinite recursive descent.
-		fatal("nested reference to file '%v' found in conf file '%v'", conf, confMap[path])
-	}
-	confMap[path] = conf
-	ifp, err := os.Open(conf)
+// A relative path in a conf file is relative to the directory of that
+// conf file like the include directive.
+func readOptsConfFile(i *int, args *[]string, origins *[]string, confMap map[string]string) (err error) {
+	origin := (*origins)[*i]
+	conf := cliGetNextArg(i, *args, &err) // conf file path
 	if err != nil {
-		fatal("conf file read failed %v: %v", conf, err)
-	}
-	defer ifp.Close()
-	s := bufio.NewScanner(ifp)
-	for s.Scan() {
-		line := strings.Trim(s.Text(), " \t")
-		if len(line) == 0 || line[0] == '#' {
-			// skip blank lines and lines that start with #.
-			continue
-		}
-		// Split on the first white space.
-		// Keep the rest intact.
-		// Examples --arg "foo bar  spam"
-		// want: ["--arg", "foo bar  spam"]
-		flds := strings.Fields(line) // ignore all white space
-		opt := flds[0]               // this is the option
-		newargs = append(newargs, opt)
-
-		// Now get the rest of the line
-		// This assumes that all options have, at most, a single argument.
-		arg := line[len(opt):]
-		arg = strings.Trim(arg, " \t")
-		if len(arg) > 0 {
-			if len(arg) > 1 && arg[0] == arg[len(arg)-1] {
-				if arg[0] == '"' || arg[0] == '\'' {
-					arg = arg[1 : len(arg)-1] // trim the leading and trailing quotes
-				}
-			}
-			newargs = append(newargs, arg)
-		}
+		return
 	}
-	if err := s.Err(); err != nil {
-		fatal("conf file read failed: %v: %v", conf, err)
+	if file, ok := confOriginFile(origin); ok && filepath.IsAbs(conf) == false {
+		conf = filepath.Join(filepath.Dir(file), conf)
+	}
+	newargs, neworigins, err := readConfFile(osFS{}, conf, "", confMap)
+	if err != nil {
+		return
 	}
 
-	// Update the slice by reference.
+	// Update the slices by reference.
 	if len(newargs) > 0 {
 		*args = append((*args)[:*i+1], append(newargs, (*args)[*i+1:]...)...)
+		*origins = append((*origins)[:*i+1], append(neworigins, (*origins)[*i+1:]...)...)
 	}
+	return nil
 }
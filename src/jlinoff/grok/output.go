@@ -0,0 +1,281 @@
+// Output formatting.
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Set when a group of lines has been printed in the no-heading format
+// so that the next group is preceded by a hunk separator. It is only
+// accessed while the print mutex is held.
+var hunkPrinted bool
+
+// hunk is a contiguous range of lines that contains one or more
+// matched lines and their context. The indexes are 0-based and
+// inclusive. Scope is the index of the enclosing scope line of the
+// first match in the hunk or -1 if there is none.
+type hunk struct {
+	Start int
+	End   int
+	Scope int
+}
+
+// makeHunks creates the hunks for the matched lines. The context windows
+// of matched lines that overlap or touch are merged into a single hunk so
+// that no line is reported more than once.
+// If scope patterns are specified, each hunk reports its enclosing scope
+// line or, for --scope-block, it is extended to cover the whole scope.
+func makeHunks(opts cliOptions, lines []string, matchedLines []int, scopes []*regexp.Regexp) (hunks []hunk) {
+	nlines := len(lines)
+	for _, i := range matchedLines {
+		start := i - opts.Before
+		if start < 0 {
+			start = 0
+		}
+		end := i + opts.After
+		if end >= nlines {
+			end = nlines - 1
+		}
+		if opts.ScopeBlock && len(scopes) > 0 {
+			if k := findScope(lines, i, scopes); k >= 0 {
+				if k < start {
+					start = k
+				}
+				if e := findScopeEnd(lines, k, scopes); e > end {
+					end = e
+				}
+			}
+		}
+		n := len(hunks)
+		if n > 0 && start <= hunks[n-1].End+1 {
+			// Overlapping or adjacent, extend the previous hunk.
+			if end > hunks[n-1].End {
+				hunks[n-1].End = end
+			}
+			continue
+		}
+		// The enclosing scope of the first match is reported for the
+		// hunk. It precedes the hunk unless it is part of the context.
+		scope := -1
+		if len(scopes) > 0 {
+			scope = findScope(lines, i, scopes)
+		}
+		hunks = append(hunks, hunk{Start: start, End: end, Scope: scope})
+	}
+	return
+}
+
+// printBinaryMatch prints a binary file that matched for --binary=match.
+// The file name is printed as usual if the lines are not reported,
+// otherwise a note replaces the lines like grep does.
+// The caller must hold the print mutex.
+func printBinaryMatch(opts cliOptions, path string) {
+	if opts.Lines == NoLines {
+		printMatches(opts, path, nil, nil)
+		return
+	}
+	fmt.Fprintf(stdout, "binary file %v matches\n", paintPath(opts, path))
+}
+
+// printMatches prints the matched file and, optionally, the matched
+// lines with their context.
+// The caller must hold the print mutex.
+func printMatches(opts cliOptions, path string, lines []string, matchedLines []int) {
+	if opts.Lines == DecoratedLines && opts.Heading == false {
+		printNoHeadingMatches(opts, path, lines, matchedLines)
+		return
+	}
+
+	cs := opts.Colors
+	if opts.Lines != RawLines {
+		// Do not print the file name for raw lines.
+		fmt.Fprintf(stdout, "%v\n", paintPath(opts, path))
+	}
+	if opts.Lines == NoLines {
+		return
+	}
+
+	matched := map[int]bool{}
+	for _, i := range matchedLines {
+		matched[i] = true
+	}
+	scopes := []*regexp.Regexp{}
+	if opts.Lines == DecoratedLines {
+		scopes = scopePatterns(opts, path)
+	}
+	context := opts.Before > 0 || opts.After > 0 || len(scopes) > 0
+	for h, hk := range makeHunks(opts, lines, matchedLines, scopes) {
+		if h > 0 && context {
+			fmt.Fprintf(stdout, "%8s %v\n", "", paint(cs.Sep, "|----------------------------------------------------------------"))
+		}
+		if hk.Scope >= 0 && hk.Scope < hk.Start {
+			line := lines[hk.Scope]
+			fmt.Fprintf(stdout, "%v%v%v", paint(cs.Line, fmt.Sprintf("%8d", hk.Scope+1)), paint(cs.Sep, " = "), paint(cs.Context, line))
+			printNewline(line)
+		}
+
+		// Context lines that follow a match in the hunk are marked with
+		// a '+', the ones that precede the first match with a '-'.
+		mark := "-"
+		for i := hk.Start; i <= hk.End; i++ {
+			line := lines[i]
+			if matched[i] {
+				mark = "+"
+				if opts.Lines == DecoratedLines {
+					fmt.Fprintf(stdout, "%v%v%v", paint(cs.Line, fmt.Sprintf("%8d", i+1)), paint(cs.Sep, " | "), colorizeLine(opts, line))
+				} else if opts.Lines == RawLines {
+					fmt.Fprintf(stdout, "%v", colorizeLine(opts, line))
+				}
+			} else if i == hk.Scope {
+				fmt.Fprintf(stdout, "%v%v%v", paint(cs.Line, fmt.Sprintf("%8d", i+1)), paint(cs.Sep, " = "), paint(cs.Context, line))
+			} else {
+				fmt.Fprintf(stdout, "%8s %v%v", "", paint(cs.Sep, "|"+mark), colorizeContextLine(opts, line))
+			}
+			printNewline(line)
+		}
+	}
+}
+
+// printNoHeadingMatches prints the matched lines in the grep compatible
+// format that editors understand:
+//
+//	path:lineno:text        matched line
+//	path:lineno:col:text    matched line with --column or --vimgrep
+//	path-lineno-text        context line
+//	path=lineno=text        enclosing scope line
+//
+// Hunks are separated by "--" when context is requested.
+func printNoHeadingMatches(opts cliOptions, path string, lines []string, matchedLines []int) {
+	scopes := scopePatterns(opts, path)
+	context := opts.Before > 0 || opts.After > 0 || len(scopes) > 0
+	matched := map[int]bool{}
+	for _, i := range matchedLines {
+		matched[i] = true
+	}
+	for _, hk := range makeHunks(opts, lines, matchedLines, scopes) {
+		if context {
+			if hunkPrinted {
+				printHunkSeparator(opts)
+			}
+			hunkPrinted = true
+		}
+		if hk.Scope >= 0 && hk.Scope < hk.Start {
+			printNoHeadingLine(opts, path, hk.Scope+1, 0, '=', lines[hk.Scope])
+		}
+		for i := hk.Start; i <= hk.End; i++ {
+			if matched[i] {
+				col := 0
+				if opts.Column {
+					col = firstMatchColumn(opts, lines[i])
+				}
+				printNoHeadingLine(opts, path, i+1, col, ':', lines[i])
+			} else if i == hk.Scope {
+				printNoHeadingLine(opts, path, i+1, 0, '=', lines[i])
+			} else {
+				printNoHeadingLine(opts, path, i+1, 0, '-', lines[i])
+			}
+		}
+	}
+}
+
+// printNoHeadingLine prints a single line with the path and line number
+// prefix. The separator is ':' for matched lines, '-' for context
+// lines and '=' for scope lines. The column is only printed if it is
+// greater than zero.
+func printNoHeadingLine(opts cliOptions, path string, lineno int, col int, sep byte, line string) {
+	cs := opts.Colors
+	sp := paint(cs.Sep, string(sep))
+	prefix := paintPath(opts, path) + sp + paint(cs.Line, fmt.Sprint(lineno)) + sp
+	if col > 0 {
+		prefix += paint(cs.Line, fmt.Sprint(col)) + sp
+	}
+	if sep == ':' {
+		fmt.Fprintf(stdout, "%v%v", prefix, colorizeLine(opts, line))
+	} else {
+		fmt.Fprintf(stdout, "%v%v", prefix, colorizeContextLine(opts, line))
+	}
+	printNewline(line)
+}
+
+// printHunkSeparator prints the separator between groups of lines.
+func printHunkSeparator(opts cliOptions) {
+	fmt.Fprintf(stdout, "%v\n", paint(opts.Colors.Sep, "--"))
+}
+
+// firstMatchColumn returns the 1-based byte column of the leftmost
+// accept pattern match in the line or 1 if there is no match.
+func firstMatchColumn(opts cliOptions, line string) int {
+	col := -1
+	for _, ps := range [][]*regexp.Regexp{opts.AcceptOrPatterns, opts.AcceptAndPatterns} {
+		for _, p := range ps {
+			loc := p.FindStringIndex(line)
+			if loc != nil && (col < 0 || loc[0] < col) {
+				col = loc[0]
+			}
+		}
+	}
+	if col < 0 {
+		return 1
+	}
+	return col + 1
+}
+
+// displayPath returns the path as it is reported. It is converted to the
+// --path-style and the --strip-prefix is removed. The paths in a --zip
+// archive are relative to the top of the archive and absolute below the
+// archive itself.
+func displayPath(opts cliOptions, path string) string {
+	switch opts.PathStyle {
+	case "relative":
+		if len(opts.Zip) > 0 {
+			path = filepath.FromSlash(ioPath(path))
+		} else if abs, err := filepath.Abs(path); err == nil {
+			if rel, err := filepath.Rel(opts.WorkDir, abs); err == nil {
+				path = rel
+			}
+		}
+	case "absolute":
+		if len(opts.Zip) > 0 {
+			path = archivePath(opts.Zip, path)
+		} else if abs, err := filepath.Abs(path); err == nil {
+			path = abs
+		}
+	case "basename":
+		path = filepath.Base(path)
+	}
+	if rest, ok := stripPathPrefix(path, opts.StripPrefix); ok {
+		path = rest
+	}
+	return path
+}
+
+// stripPathPrefix removes the prefix from the path if it ends at a path
+// component boundary, /tmp/t is not a prefix of /tmp/t2/a.txt. It returns
+// false if the prefix does not match or if nothing would be left.
+func stripPathPrefix(path string, prefix string) (string, bool) {
+	sep := string(filepath.Separator)
+	if len(prefix) == 0 || strings.HasPrefix(path, prefix) == false {
+		return path, false
+	}
+	rest := path[len(prefix):]
+	if strings.HasSuffix(prefix, sep) == false && strings.HasPrefix(rest, sep) == false {
+		return path, false
+	}
+	rest = strings.TrimLeft(rest, sep)
+	if len(rest) == 0 {
+		return path, false
+	}
+	return rest, true
+}
+
+// printNewline prints a new line if it is needed.
+func printNewline(line string) {
+	if len(line) == 0 {
+		fmt.Fprintf(stdout, "\n")
+	} else if line[len(line)-1] != '\n' {
+		fmt.Fprintf(stdout, "\n")
+	}
+}
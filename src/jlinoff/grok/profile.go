@@ -0,0 +1,83 @@
+// Conf file profiles.
+package main
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// confProfile is a named bundle of arguments that is defined by a
+// "[profile NAME]" section in a conf file and applied by -P NAME.
+type confProfile struct {
+	Name    string
+	Origin  string   // file:line of the section header
+	Extends []string // profiles whose arguments come first
+	Args    []string
+	Origins []string // file:line of each argument
+}
+
+// The profiles that were found in the conf files. A profile that is
+// defined again replaces the earlier definition.
+var confProfiles = map[string]*confProfile{}
+
+// Guards confProfiles, the directory conf files are read by the walkers
+// in parallel.
+var confProfilesMutex sync.Mutex
+
+// The profile section header.
+var profileHeaderRe = regexp.MustCompile(`^\s*\[\s*profile\s+([^\s\]]+)\s*\]\s*(#.*)?$`)
+
+// expandProfile returns the arguments of a profile preceded by the
+// arguments of the profiles that it extends. The seen map holds the
+// profiles that are being expanded to catch profiles that extend
+// themselves.
+func expandProfile(name string, seen map[string]bool) (args []string, origins []string, err error) {
+	profile, found := confProfiles[name]
+	if found == false {
+		return nil, nil, optionErrorf("unknown profile '%v', use --list-profiles to see the available profiles", name)
+	}
+	if seen[name] {
+		return nil, nil, optionErrorf("%v: nested reference to profile '%v'", profile.Origin, name)
+	}
+	seen[name] = true
+	defer delete(seen, name)
+	for _, base := range profile.Extends {
+		bargs, borigins, err := expandProfile(base, seen)
+		if err != nil {
+			return nil, nil, err
+		}
+		args = append(args, bargs...)
+		origins = append(origins, borigins...)
+	}
+	args = append(args, profile.Args...)
+	origins = append(origins, profile.Origins...)
+	return
+}
+
+// listProfiles prints the available profiles sorted by name.
+func listProfiles(w io.Writer) {
+	names := []string{}
+	for name := range confProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		profile := confProfiles[name]
+		fmt.Fprintf(w, "%v\n", name)
+		fmt.Fprintf(w, "    defined in : %v\n", profile.Origin)
+		if len(profile.Extends) > 0 {
+			fmt.Fprintf(w, "    extends    : %v\n", strings.Join(profile.Extends, " "))
+		}
+		if len(profile.Args) > 0 {
+			qargs := []string{}
+			for _, arg := range profile.Args {
+				qargs = append(qargs, quote(arg))
+			}
+			fmt.Fprintf(w, "    arguments  : %v\n", strings.Join(qargs, " "))
+		}
+	}
+}
@@ -0,0 +1,77 @@
+// Enclosing scope detection.
+package main
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Built-in scope patterns by file extension. They are used by
+// --scope-auto when no --scope patterns were specified.
+var scopeDefaults = map[string]*regexp.Regexp{}
+
+func init() {
+	c := `^\w.*\(.*\)\s*\{`
+	java := `^\s*((public|protected|private|static|final|abstract|synchronized)\s+)+[\w<>\[\], ]+\s*\(|^\s*(public\s+|abstract\s+|final\s+)*(class|interface|enum)\s`
+	js := `^\s*(export\s+)?(async\s+)?function\b|^\s*(export\s+)?class\s|^\s*\w+\s*\(.*\)\s*\{`
+	m := map[string]string{
+		".c":    c,
+		".cc":   c,
+		".cpp":  c,
+		".cxx":  c,
+		".h":    c,
+		".hh":   c,
+		".hpp":  c,
+		".go":   `^func `,
+		".java": java,
+		".js":   js,
+		".pl":   `^\s*sub\s`,
+		".php":  `^\s*((public|protected|private|static|abstract|final)\s+)*(function|class|interface|trait)\s`,
+		".py":   `^\s*(async\s+)?(def|class)\s`,
+		".rb":   `^\s*(def|class|module)\s`,
+		".rs":   `^\s*(pub(\(\w+\))?\s+)?(async\s+)?(fn|impl|struct|enum|trait|mod)\b`,
+		".sh":   `^\s*(function\s+\w+|\w+\s*\(\s*\))`,
+		".ts":   js,
+	}
+	for ext, p := range m {
+		scopeDefaults[ext] = regexp.MustCompile(p)
+	}
+}
+
+// scopePatterns returns the scope patterns to use for a file.
+// Explicit --scope patterns have priority over the built-in defaults.
+func scopePatterns(opts cliOptions, path string) []*regexp.Regexp {
+	if len(opts.ScopePatterns) > 0 {
+		return opts.ScopePatterns
+	}
+	if opts.ScopeAuto {
+		if p, ok := scopeDefaults[strings.ToLower(filepath.Ext(path))]; ok {
+			return []*regexp.Regexp{p}
+		}
+	}
+	return nil
+}
+
+// findScope returns the index of the nearest line at or before line i
+// that matches one of the scope patterns or -1 if there is none.
+func findScope(lines []string, i int, scopes []*regexp.Regexp) int {
+	for ; i >= 0; i-- {
+		if checkOrConditions(lines[i], scopes) {
+			return i
+		}
+	}
+	return -1
+}
+
+// findScopeEnd returns the index of the last line of the scope that
+// starts at line i. The scope ends just before the next scope line or at
+// the end of the file.
+func findScopeEnd(lines []string, i int, scopes []*regexp.Regexp) int {
+	for k := i + 1; k < len(lines); k++ {
+		if checkOrConditions(lines[k], scopes) {
+			return k - 1
+		}
+	}
+	return len(lines) - 1
+}
@@ -0,0 +1,32 @@
+// File status details for Linux.
+package main
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// accessTime returns the last access time of a file.
+func accessTime(stat os.FileInfo) (time.Time, bool) {
+	if st, ok := stat.Sys().(*syscall.Stat_t); ok {
+		return time.Unix(st.Atim.Unix()), true
+	}
+	return time.Time{}, false
+}
+
+// changeTime returns the last status change time of a file.
+func changeTime(stat os.FileInfo) (time.Time, bool) {
+	if st, ok := stat.Sys().(*syscall.Stat_t); ok {
+		return time.Unix(st.Ctim.Unix()), true
+	}
+	return time.Time{}, false
+}
+
+// fileIdentity returns the device and inode of a file.
+func fileIdentity(stat os.FileInfo) (dev uint64, ino uint64, ok bool) {
+	if st, ok := stat.Sys().(*syscall.Stat_t); ok {
+		return uint64(st.Dev), uint64(st.Ino), true
+	}
+	return 0, 0, false
+}
@@ -0,0 +1,25 @@
+//go:build !linux && !darwin
+
+// File status details for the other platforms.
+package main
+
+import (
+	"os"
+	"time"
+)
+
+// accessTime is not available.
+func accessTime(stat os.FileInfo) (time.Time, bool) {
+	return time.Time{}, false
+}
+
+// changeTime is not available.
+func changeTime(stat os.FileInfo) (time.Time, bool) {
+	return time.Time{}, false
+}
+
+// fileIdentity is not available, files are identified by their
+// canonical path.
+func fileIdentity(stat os.FileInfo) (dev uint64, ino uint64, ok bool) {
+	return 0, 0, false
+}
@@ -0,0 +1,231 @@
+// File type registry.
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// fileType maps a type name to the file name globs and the shebang
+// interpreters of the files of that type.
+type fileType struct {
+	Name         string
+	Globs        []string // matched against the base name
+	Interpreters []string // matched against the #! line
+}
+
+// The file types by name. The built-in types can be extended by
+// --type-add.
+var fileTypes = map[string]*fileType{}
+
+// The built-in file types. The specification is a comma separated list
+// of globs, the entries that start with #! are interpreters.
+var builtinFileTypes = map[string]string{
+	"ada":       "*.adb,*.ads",
+	"asm":       "*.asm,*.s,*.S",
+	"awk":       "*.awk,#!awk,#!gawk,#!mawk",
+	"c":         "*.c,*.h",
+	"clojure":   "*.clj,*.cljc,*.cljs,*.edn",
+	"cmake":     "CMakeLists.txt,*.cmake",
+	"conf":      "*.conf,*.cfg,*.ini,.grokrc",
+	"cpp":       "*.cc,*.cpp,*.cxx,*.c++,*.hh,*.hpp,*.hxx,*.h++,*.inl",
+	"cs":        "*.cs",
+	"css":       "*.css,*.scss,*.sass,*.less",
+	"csv":       "*.csv,*.tsv",
+	"d":         "*.d",
+	"dart":      "*.dart",
+	"docker":    "Dockerfile,Dockerfile.*,*.dockerfile",
+	"elixir":    "*.ex,*.exs",
+	"elm":       "*.elm",
+	"erlang":    "*.erl,*.hrl",
+	"fortran":   "*.f,*.F,*.f77,*.f90,*.f95,*.for",
+	"fsharp":    "*.fs,*.fsi,*.fsx",
+	"go":        "*.go",
+	"gradle":    "*.gradle,*.gradle.kts",
+	"groovy":    "*.groovy,*.gvy",
+	"haskell":   "*.hs,*.lhs",
+	"html":      "*.htm,*.html,*.xhtml",
+	"java":      "*.java",
+	"js":        "*.js,*.jsx,*.mjs,*.cjs,#!node",
+	"json":      "*.json",
+	"julia":     "*.jl,#!julia",
+	"kotlin":    "*.kt,*.kts",
+	"lisp":      "*.lisp,*.lsp,*.el",
+	"lua":       "*.lua,#!lua",
+	"make":      "Makefile,makefile,GNUmakefile,*.mk,*.mak",
+	"markdown":  "*.md,*.markdown",
+	"matlab":    "*.m",
+	"nim":       "*.nim",
+	"ocaml":     "*.ml,*.mli",
+	"perl":      "*.pl,*.pm,*.t,#!perl",
+	"php":       "*.php,*.phtml,#!php",
+	"protobuf":  "*.proto",
+	"ps":        "*.ps1,*.psm1,*.psd1,#!pwsh",
+	"py":        "*.py,*.pyi,*.pyw,#!python",
+	"r":         "*.R,*.r,*.Rmd,#!Rscript",
+	"rst":       "*.rst",
+	"ruby":      "*.rb,*.rake,*.gemspec,Gemfile,Rakefile,#!ruby",
+	"rust":      "*.rs",
+	"scala":     "*.scala,*.sc,#!scala",
+	"sh":        "*.sh,*.bash,*.zsh,*.ksh,.bashrc,.bash_profile,.profile,.zshrc,#!sh,#!bash,#!dash,#!ksh,#!zsh",
+	"sql":       "*.sql",
+	"swift":     "*.swift",
+	"tcl":       "*.tcl,#!tclsh,#!wish",
+	"tex":       "*.tex,*.sty,*.cls,*.bib",
+	"terraform": "*.tf,*.tfvars",
+	"test":      "*_test.go,test_*.py,*_test.py,*Test.java,*.test.js,*.spec.js,*.test.ts,*.spec.ts",
+	"toml":      "*.toml",
+	"ts":        "*.ts,*.tsx,*.mts,*.cts",
+	"txt":       "*.txt",
+	"vb":        "*.vb,*.vbs",
+	"verilog":   "*.v,*.vh,*.sv,*.svh",
+	"vhdl":      "*.vhd,*.vhdl",
+	"vim":       "*.vim,.vimrc",
+	"xml":       "*.xml,*.xsd,*.xsl,*.xslt,*.svg",
+	"yaml":      "*.yaml,*.yml",
+	"zig":       "*.zig",
+}
+
+// Valid type name.
+var typeNameRe = regexp.MustCompile(`^[A-Za-z0-9_+.-]+$`)
+
+// The version suffix of an interpreter, like the 3.11 in python3.11.
+var interpreterVersionRe = regexp.MustCompile(`[0-9.]+$`)
+
+func init() {
+	for name, spec := range builtinFileTypes {
+		addFileType(name, spec)
+	}
+}
+
+// addFileType adds globs and interpreters to a file type. The type is
+// created if it does not exist.
+func addFileType(name string, spec string) {
+	ft, found := fileTypes[name]
+	if found == false {
+		ft = &fileType{Name: name}
+		fileTypes[name] = ft
+	}
+	for _, entry := range strings.Split(spec, ",") {
+		if strings.HasPrefix(entry, "#!") {
+			ft.Interpreters = append(ft.Interpreters, entry[2:])
+		} else if len(entry) > 0 {
+			ft.Globs = append(ft.Globs, entry)
+		}
+	}
+}
+
+// parseTypeAdd parses a --type-add NAME:SPEC definition and adds it to
+// the registry.
+func parseTypeAdd(def string) error {
+	flds := strings.SplitN(def, ":", 2)
+	if len(flds) != 2 || typeNameRe.MatchString(flds[0]) == false || len(flds[1]) == 0 {
+		return fmt.Errorf("expected NAME:GLOB[,GLOB...] like 'web:*.html,*.css'")
+	}
+	for _, entry := range strings.Split(flds[1], ",") {
+		if strings.HasPrefix(entry, "#!") {
+			continue
+		}
+		if _, err := filepath.Match(entry, ""); err != nil {
+			return fmt.Errorf("invalid glob '%v': %v", entry, err)
+		}
+	}
+	addFileType(flds[0], flds[1])
+	return nil
+}
+
+// matchFileType returns true if the file is one of the types.
+// The shebang line is only read for files that have no extension
+// because scripts normally do not have one.
+func matchFileType(types []string, path string) bool {
+	base := filepath.Base(path)
+	checkShebang := false
+	for _, name := range types {
+		ft := fileTypes[name]
+		for _, g := range ft.Globs {
+			if m, _ := filepath.Match(g, base); m {
+				return true
+			}
+		}
+		if len(ft.Interpreters) > 0 {
+			checkShebang = true
+		}
+	}
+	if checkShebang == false || len(filepath.Ext(base)) > 0 {
+		return false
+	}
+	interp := shebangInterpreter(path)
+	if len(interp) == 0 {
+		return false
+	}
+	for _, name := range types {
+		for _, i := range fileTypes[name].Interpreters {
+			if interp == i || interpreterVersionRe.ReplaceAllString(interp, "") == i {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// shebangInterpreter returns the name of the interpreter in the #! line
+// of a file or an empty string if there is none. For /usr/bin/env the
+// next argument is the interpreter.
+func shebangInterpreter(path string) string {
+	file, err := fileSystem.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+	line, _ := bufio.NewReader(io.LimitReader(file, 256)).ReadString('\n')
+	if strings.HasPrefix(line, "#!") == false {
+		return ""
+	}
+	flds := strings.Fields(line[2:])
+	if len(flds) == 0 {
+		return ""
+	}
+	interp := filepath.Base(flds[0])
+	if interp == "env" {
+		for _, f := range flds[1:] {
+			if strings.HasPrefix(f, "-") == false && strings.Contains(f, "=") == false {
+				return f
+			}
+		}
+		return ""
+	}
+	return interp
+}
+
+// checkFileTypes returns an error if one of the type names is not in the
+// registry.
+func checkFileTypes(types []string) error {
+	for _, name := range types {
+		if _, found := fileTypes[name]; found == false {
+			return optionErrorf("unknown file type '%v', use --type-list to see the available types", name)
+		}
+	}
+	return nil
+}
+
+// listFileTypes prints the file types sorted by name.
+func listFileTypes(w io.Writer) {
+	names := []string{}
+	for name := range fileTypes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		ft := fileTypes[name]
+		entries := append([]string{}, ft.Globs...)
+		for _, i := range ft.Interpreters {
+			entries = append(entries, "#!"+i)
+		}
+		fmt.Fprintf(w, "%-12v %v\n", name, strings.Join(entries, ", "))
+	}
+}
@@ -0,0 +1,158 @@
+// Directory tree walk.
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Maximum number of extra goroutines that walk directories. The
+// goroutine that finds a subdirectory walks it itself if there is no
+// free slot so the walk never waits for one.
+var maxwalk chan bool
+
+// The walker goroutines that are running.
+var walkers sync.WaitGroup
+
+// walk the directory tree looking for files that match.
+// The root is the directory or file that was specified on the command
+// line, the globs are matched against the path relative to it.
+// The directory entries are classified by their type bits so that the
+// walk only stats the directories, which is needed to detect the ones
+// that were already searched, and the symbolic links. The files are
+// stat'ed by the jobs that check them.
+// Hidden entries and files above --min-depth are skipped before they are
+// stat'ed, the root itself is never hidden.
+// The opts are passed by value so that a directory can change them for
+// its subtree, like --dir-include does once a directory matches.
+func walk(ctx context.Context, opts cliOptions, root string, path string, fs *findStats, depth int) {
+	infov2(opts, "checking: %v %v '%v'", depth, opts.MaxDepth, path)
+	if opts.MaxDepth >= 0 && depth > opts.MaxDepth {
+		return
+	}
+
+	// If this is a file, process it.
+	// If it is a directory, look at all of the entries.
+	stat, err := statPath(path)
+	if err != nil {
+		reportSearchError(opts, fs, err)
+		return
+	}
+
+	if stat.IsDir() == false {
+		if depth >= opts.MinDepth {
+			checkFileParallel(ctx, opts, root, path, stat, fs)
+		}
+		return
+	}
+
+	if pruneDir(opts, root, path) {
+		infov2(opts, "pruning '%v'", path)
+		return
+	}
+	// A root is always walked because the walk of another root may
+	// have been cut short by -m, --dir-include or a .grok.conf, the
+	// files that were already searched are skipped one by one.
+	if visited.firstVisit(path, stat) == false && path != root {
+		infov2(opts, "skipping directory that was already searched: '%v'", path)
+		return
+	}
+
+	entries, rerr := fileSystem.ReadDir(path)
+	if rerr != nil {
+		reportSearchError(opts, fs, &searchError{Kind: unreadableDir, Path: path, Err: rerr})
+		return
+	}
+	if path != root {
+		if marker := pruneMarker(opts, path, entries); len(marker) > 0 {
+			infov2(opts, "pruning '%v', it contains %v", path, marker)
+			return
+		}
+	}
+
+	// A directory conf file changes the options for the subtree.
+	if opts.DirConf {
+		if conf := findDirConf(path, entries); len(conf) > 0 {
+			infov2(opts, "applying conf file '%v'", conf)
+			var cerr error
+			opts, cerr = loadDirConf(opts, path, conf)
+			if cerr != nil {
+				reportSearchError(opts, fs, &searchError{Kind: confError, Path: conf, Err: cerr})
+			}
+		}
+	}
+
+	// Once a directory matches --dir-include, its whole subtree is
+	// searched. The directories above it are only walked.
+	if len(opts.DirIncludePatterns) > 0 && matchDirInclude(opts, root, path) {
+		infov2(opts, "including subtree '%v'", path)
+		opts.DirIncludePatterns = nil
+	}
+
+	// The files in this directory are skipped above --min-depth and
+	// outside of --dir-include.
+	checkFiles := depth >= opts.MinDepth && len(opts.DirIncludePatterns) == 0
+
+	for _, entry := range entries {
+		if searchStopped(ctx) {
+			return
+		}
+		newPath := filepath.Join(path, entry.Name())
+		if opts.Hidden == false && strings.HasPrefix(entry.Name(), ".") {
+			infov2(opts, "skipping hidden entry: '%v'", newPath)
+			continue
+		}
+
+		switch typ := entry.Type(); {
+		case typ.IsDir():
+			walkDir(ctx, opts, root, newPath, fs, depth+1)
+		case typ&os.ModeSymlink != 0:
+			// The target decides what the link is.
+			stat, serr := statPath(newPath)
+			if serr != nil {
+				reportSearchError(opts, fs, serr)
+			} else if stat.IsDir() {
+				walkDir(ctx, opts, root, newPath, fs, depth+1)
+			} else if checkFiles {
+				checkFileParallel(ctx, opts, root, newPath, stat, fs)
+			}
+		case checkFiles:
+			checkFileParallel(ctx, opts, root, newPath, nil, fs)
+		default:
+			infov2(opts, "skipping file above the minimum depth or outside of --dir-include: '%v'", newPath)
+		}
+	}
+}
+
+// walkDir walks a subdirectory in a new goroutine if a slot is free.
+// Otherwise it is walked by the caller.
+func walkDir(ctx context.Context, opts cliOptions, root string, path string, fs *findStats, depth int) {
+	select {
+	case maxwalk <- true:
+		walkers.Add(1)
+		go func() {
+			defer walkers.Done()
+			defer func() { <-maxwalk }() // give up the slot
+			walk(ctx, opts, root, path, fs, depth)
+		}()
+	default:
+		walk(ctx, opts, root, path, fs, depth)
+	}
+}
+
+// statPath returns the file info of a path, links are followed. If that
+// fails, the link itself is checked to tell broken links apart from
+// paths that cannot be read.
+func statPath(path string) (os.FileInfo, *searchError) {
+	stat, err := fileSystem.Stat(path)
+	if err == nil {
+		return stat, nil
+	}
+	if lstat, lerr := fileSystem.Lstat(path); lerr == nil && lstat.Mode()&os.ModeSymlink != 0 {
+		return nil, &searchError{Kind: brokenLink, Path: path, Err: err}
+	}
+	return nil, &searchError{Kind: unreadableFile, Path: path, Err: err}
+}
@@ -69,4 +69,8 @@ function runcmdst() {
 Name=$(basename "$0" | cut -d. -f1)
 PUT=../bin/grok
 
+# Make sure that the user environment does not change the output.
+unset NO_COLOR CLICOLOR_FORCE GROK_COLORS GROK_OPTS
+export XDG_CONFIG_HOME=/tmp/grok-no-such-config-home
+
 
@@ -54,6 +54,7 @@ for t in $(ls -1 test[0-9][0-9]*.sh) ; do
         sed -E \
             -e 's@^[ ]+[0-9]+ \| @  LINENO \| @' \
             -e 's@INFO[ ]+[0-9]+ -@INFO  LINENO -@' \
+            -e 's@(ERROR|WARNING)[ ]+[0-9]+ -@\1  LINENO -@' \
             -e 's@^[0-9]+/[0-9]+/[0-9]+ [0-9]+:[0-9]+:[0-9]+@YYYY/MM/DD hh:mm:ss@' \
         | \
         grep -v 'summary: files tested : ' | \
@@ -63,6 +64,7 @@ for t in $(ls -1 test[0-9][0-9]*.sh) ; do
         sed -E \
             -e 's@^[ ]+[0-9]+ \| @  LINENO \| @' \
             -e 's@INFO[ ]+[0-9]+ -@INFO  LINENO -@' \
+            -e 's@(ERROR|WARNING)[ ]+[0-9]+ -@\1  LINENO -@' \
             -e 's@^[0-9]+/[0-9]+/[0-9]+ [0-9]+:[0-9]+:[0-9]+@YYYY/MM/DD hh:mm:ss@' \
         | \
         grep -v 'summary: files tested : ' | \
@@ -0,0 +1,13 @@
+#!/bin/bash
+#
+# Test the grep and editor compatible output formats.
+#
+
+# ================================================================
+# Includes
+# ================================================================
+Location="$(cd $(dirname $0) && pwd)"
+source $Location/test-utils.sh
+
+$PUT -M 1 -W --vimgrep -a waldo test08.txt
+$PUT -M 1 -W --no-heading -y 1 -a 'lobortis id' test08.txt test09.txt
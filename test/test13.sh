@@ -0,0 +1,17 @@
+#!/bin/bash
+#
+# Test that overlapping context windows are merged into hunks.
+#
+
+# ================================================================
+# Includes
+# ================================================================
+Location="$(cd $(dirname $0) && pwd)"
+source $Location/test-utils.sh
+
+$PUT -Wlyza 1 1 waldo test08.txt
+$PUT -W --no-heading -yza 1 1 waldo test08.txt
+
+# The context cannot be negative.
+runcmdst 2 2 $PUT -W -y -3 -l -a waldo test08.txt
+runcmdst 2 2 $PUT -W -z -1 -l -a waldo test08.txt
@@ -0,0 +1,16 @@
+#!/usr/bin/env python
+# Sample file for the enclosing scope tests.
+
+
+class Waldo(object):
+    def __init__(self):
+        self.name = 'waldo'
+
+    def where(self):
+        place = 'here'
+        return place
+
+
+def find():
+    w = Waldo()
+    return w.where()
@@ -0,0 +1,14 @@
+#!/bin/bash
+#
+# Test the enclosing scope reporting.
+#
+
+# ================================================================
+# Includes
+# ================================================================
+Location="$(cd $(dirname $0) && pwd)"
+source $Location/test-utils.sh
+
+$PUT -Wl --scope-auto -a 'place|w\.where' test14.py
+$PUT -W --no-heading --scope '^\s*def ' -a 'place' test14.py
+$PUT -Wl --scope-block --scope '^\s*def ' -a 'w\.where' test14.py
@@ -0,0 +1,14 @@
+#!/bin/bash
+#
+# Test selecting the reported lines separately from the file acceptance.
+#
+
+# ================================================================
+# Includes
+# ================================================================
+Location="$(cd $(dirname $0) && pwd)"
+source $Location/test-utils.sh
+
+$PUT -M 1 -Wl --invert-lines -a waldo test08.txt test09.txt
+$PUT -M 1 -Wl --show 'Lorem|ligula' -a waldo test08.txt test09.txt
+$PUT -M 1 -Wl --invert-lines --show 'a' -a waldo test08.txt
@@ -0,0 +1,14 @@
+#!/bin/bash
+#
+# Test the highlighting of overlapping matches from multiple patterns
+# and the GROK_COLORS palette.
+#
+
+# ================================================================
+# Includes
+# ================================================================
+Location="$(cd $(dirname $0) && pwd)"
+source $Location/test-utils.sh
+
+$PUT -CWl -a 'waldo' -a 'ald|nisi' -a '[0-9]' test08.txt
+GROK_COLORS='path=35:line=32:sep=36:match=4,7' $PUT -CW --vimgrep -a 'waldo' -a 'nisi' test08.txt
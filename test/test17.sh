@@ -0,0 +1,20 @@
+#!/bin/bash
+#
+# Test the automatic color handling.
+#
+
+# ================================================================
+# Includes
+# ================================================================
+Location="$(cd $(dirname $0) && pwd)"
+source $Location/test-utils.sh
+
+# The output is not a terminal so auto mode does not colorize.
+$PUT -W -a waldo test08.txt
+CLICOLOR_FORCE=1 $PUT -W -a waldo test08.txt
+NO_COLOR=1 CLICOLOR_FORCE=1 $PUT -W -a waldo test08.txt
+NO_COLOR=1 $PUT -W --color=always -a waldo test08.txt
+$PUT -W -C --color=never -a waldo test08.txt
+
+# Strip the host and directory from the hyperlink.
+$PUT -W --color=always --hyperlink -a waldo test08.txt | sed -e 's@file://[^/]*/.*/test08.txt@file://HOST/DIR/test08.txt@'
@@ -0,0 +1,17 @@
+#!/bin/bash
+#
+# Test early termination: --max-count, --max-total and --quiet.
+#
+
+# ================================================================
+# Includes
+# ================================================================
+Location="$(cd $(dirname $0) && pwd)"
+source $Location/test-utils.sh
+
+$PUT -M 1 -Wl --max-count 1 -a waldo test08.txt test09.txt
+$PUT -M 1 -Wl --max-total 4 -a waldo -a Lorem test08.txt test09.txt
+$PUT -M 1 -W --max-total 1 -a Lorem test08.txt test09.txt
+runcmd $PUT -W -q -a waldo .
+runcmdst 1 1 $PUT -W -q -a 'no-such-thing-''anywhere' test08.txt
+$PUT -M 1 -W -s -a waldo test08.txt
@@ -0,0 +1,33 @@
+#!/bin/bash
+#
+# Test graceful cancellation: broken pipes, timeouts and file timeouts.
+#
+
+# ================================================================
+# Includes
+# ================================================================
+Location="$(cd $(dirname $0) && pwd)"
+source $Location/test-utils.sh
+
+# A broken pipe stops the search, the files that were not searched make
+# it an error.
+# The output must not fit in the pipe buffer.
+Big=/tmp/$Name-$$.txt
+seq 1 100000 > $Big
+$PUT -M 1 -Wl -a '.' $Big | head -2 | sed -e "s@$Big@BIG@"
+echo "status=${PIPESTATUS[0]}"
+rm -f $Big
+
+# The global deadline expires before any file is tested.
+$PUT -W -s --timeout 1ns -a waldo test08.txt
+
+# Opening a fifo blocks until there is a writer, like a hung NFS file.
+Fifo=/tmp/$Name-$$.fifo
+rm -f $Fifo
+mkfifo $Fifo
+$PUT -M 1 -W -s --file-timeout 200ms -a waldo $Fifo test08.txt | grep -v 'summary: files tested'
+
+# A search that was cut short by --timeout is an error, not a miss.
+$PUT -M 1 -W --timeout 200ms -a no-match $Fifo
+echo "status=$?"
+rm -f $Fifo
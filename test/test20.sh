@@ -0,0 +1,31 @@
+#!/bin/bash
+#
+# Test the exit status and the error categories in the summary.
+#
+
+# ================================================================
+# Includes
+# ================================================================
+Location="$(cd $(dirname $0) && pwd)"
+source $Location/test-utils.sh
+
+# Invalid options are errors.
+runcmdst 2 2 "$PUT --no-such-option 2>/dev/null"
+runcmdst 2 2 "$PUT -a '(' test08.txt 2>/dev/null"
+runcmdst 2 2 "$PUT --max-count 2>/dev/null"
+
+# No match.
+runcmdst 1 1 $PUT -W -a 'no-such-thing-''anywhere' test08.txt
+
+# A timeout is an error even if nothing matched.
+runcmdst 2 2 $PUT -W --timeout 1ns -a 'no-such-thing-''anywhere' test08.txt
+
+# A broken link is a warning unless --warnings-as-errors is set.
+Dir=/tmp/grok-$Name
+rm -rf $Dir
+mkdir $Dir
+echo 'waldo was here' > $Dir/waldo.txt
+ln -s $Dir/no-such-file $Dir/broken.txt
+runcmd $PUT -W -s -a waldo $Dir
+runcmdst 2 2 "$PUT -W --warnings-as-errors -a waldo $Dir >/dev/null"
+rm -rf $Dir
@@ -0,0 +1,38 @@
+#!/bin/bash
+#
+# Test the date/time specifications, --newer-than-file and --time-field.
+#
+
+# ================================================================
+# Includes
+# ================================================================
+Location="$(cd $(dirname $0) && pwd)"
+source $Location/test-utils.sh
+
+Dir=/tmp/grok-$Name
+rm -rf $Dir
+mkdir $Dir
+for f in old ref hour new ; do
+    echo "waldo was here" > $Dir/$f.txt
+done
+touch -d '2001-01-01T00:00:00Z' $Dir/old.txt
+touch -d '2010-06-15T12:00:00Z' $Dir/ref.txt
+touch -d '90 minutes ago' $Dir/hour.txt
+touch -a -d '2030-01-01T00:00:00Z' $Dir/new.txt
+
+# Relative, the hours are not minutes.
+runcmd $PUT -M 1 -W -a waldo -n 2h $Dir
+
+# Absolute dates with and without time zones.
+runcmd $PUT -M 1 -W -a waldo -o 2010-06-15T12:00:00Z $Dir
+runcmd $PUT -M 1 -W -a waldo -n 2010-06-15T13:00:00+01:00 -o 2010-06-15T12:00:00.000Z $Dir
+runcmd $PUT -M 1 -W -a waldo -n 2000-12-31 -o 2001-01-02 $Dir
+runcmd $PUT -M 1 -W -a waldo -n yesterday $Dir
+runcmdst 2 2 "$PUT -W -a waldo -n 2001-13-01 $Dir 2>/dev/null"
+
+# Reference files and time fields.
+runcmd $PUT -M 1 -W -a waldo --newer-than-file $Dir/ref.txt $Dir
+runcmd $PUT -M 1 -W -a waldo --time-field=atime -n 2029-12-31 $Dir
+runcmdst 1 1 $PUT -M 1 -W -a waldo --time-field ctime -o 2005-01-01 $Dir
+runcmdst 2 2 "$PUT -W -a waldo --time-field btime $Dir 2>/dev/null"
+rm -rf $Dir
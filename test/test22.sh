@@ -0,0 +1,44 @@
+#!/bin/bash
+#
+# Test the conf file syntax: multiple arguments per line, quoting,
+# variable expansion and include directives.
+#
+
+# ================================================================
+# Includes
+# ================================================================
+Location="$(cd $(dirname $0) && pwd)"
+source $Location/test-utils.sh
+
+Dir=/tmp/grok-$Name
+rm -rf $Dir
+mkdir -p $Dir/sub
+cat >$Dir/main.conf <<'EOF'
+# Several arguments on one line.
+-M 1 -W -l   # trailing comment
+-a "${GROK_TEST_WORD}\b"
+-d 'waldo lobortis' -d waldo\ fringilla
+include sub/common.conf
+EOF
+cat >$Dir/sub/common.conf <<'EOF'
+-i \.txt$
+EOF
+cat >$Dir/loop.conf <<'EOF'
+-W
+include sub/loop.conf
+EOF
+cat >$Dir/sub/loop.conf <<'EOF'
+include ../loop.conf
+EOF
+cat >$Dir/bad.conf <<'EOF'
+-W
+-a 'unterminated
+EOF
+
+export GROK_TEST_WORD=waldo
+runcmd $PUT -c $Dir/main.conf test08.txt test09.txt test10.txt
+runcmdst 2 2 "$PUT -c $Dir/loop.conf test08.txt 2>$Dir/err.log"
+sed -e 's/^.* - //' $Dir/err.log
+runcmdst 2 2 "$PUT -c $Dir/bad.conf test08.txt 2>$Dir/err.log"
+sed -e 's/^.* - //' $Dir/err.log
+rm -rf $Dir
@@ -0,0 +1,42 @@
+#!/bin/bash
+#
+# Test the config discovery: the user config file, the nearest .grokrc,
+# GROK_OPTS and the command line in order of increasing precedence.
+#
+
+# ================================================================
+# Includes
+# ================================================================
+Location="$(cd $(dirname $0) && pwd)"
+source $Location/test-utils.sh
+
+Dir=/tmp/grok-$Name
+rm -rf $Dir
+mkdir -p $Dir/xdg/grok $Dir/project/src/deep
+export XDG_CONFIG_HOME=$Dir/xdg
+cat >$Dir/xdg/grok/config <<'EOF'
+-M 1 -W -z 2
+EOF
+cat >$Dir/project/.grokrc <<'EOF'
+-z 1
+EOF
+cp test08.txt $Dir/project/src/deep/
+cd $Dir/project/src
+
+PATH=$Location/../bin:$PATH
+PUT=grok
+runcmd $PUT -l -a waldo .
+runcmd "GROK_OPTS='-z 0' $PUT -l -a waldo ."
+runcmd "GROK_OPTS='-z 0' $PUT -l -a waldo -z 3 ."
+runcmd $PUT --no-config -l -a waldo .
+
+# Trace the origin of the options.
+$PUT -vv -l -a waldo . 2>&1 >/dev/null | grep -E 'option:|config:' | sed -e 's/^.* - //'
+
+# A relative -c in the .grokrc is relative to the .grokrc, not to the
+# current directory.
+echo '-c extra.conf' >> $Dir/project/.grokrc
+echo '-z 0' > $Dir/project/extra.conf
+runcmd $PUT -l -a waldo .
+cd $Location
+rm -rf $Dir
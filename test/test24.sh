@@ -0,0 +1,44 @@
+#!/bin/bash
+#
+# Test profiles in conf files: -P, extends and --list-profiles.
+#
+
+# ================================================================
+# Includes
+# ================================================================
+Location="$(cd $(dirname $0) && pwd)"
+source $Location/test-utils.sh
+
+Dir=/tmp/grok-$Name
+rm -rf $Dir
+mkdir -p $Dir
+cat >$Dir/profiles.conf <<'EOF'
+# Always used.
+-M 1 -W
+
+[profile text]
+-i '\.txt$'
+
+[profile waldo]   # the waldo lines
+extends text
+-l -a waldo
+
+[profile lorem]
+extends waldo
+-a Lorem
+
+[profile loop1]
+extends loop2
+[profile loop2]
+extends loop1
+EOF
+
+runcmd $PUT -c $Dir/profiles.conf -P waldo test08.txt test09.sh
+runcmd $PUT -c $Dir/profiles.conf -P lorem test08.txt
+runcmdst 1 1 $PUT -c $Dir/profiles.conf -P text -a waldo test09.sh
+runcmd "$PUT -c $Dir/profiles.conf --list-profiles | sed -e 's@$Dir@DIR@'"
+runcmdst 2 2 "$PUT -c $Dir/profiles.conf -P nope 2>$Dir/err.log"
+sed -e 's/^.* - //' -e "s@$Dir@DIR@" $Dir/err.log
+runcmdst 2 2 "$PUT -c $Dir/profiles.conf -P loop1 2>$Dir/err.log"
+sed -e 's/^.* - //' -e "s@$Dir@DIR@" $Dir/err.log
+rm -rf $Dir
@@ -0,0 +1,30 @@
+#!/bin/bash
+#
+# Test the file type registry: --type, --type-not and --type-add.
+#
+
+# ================================================================
+# Includes
+# ================================================================
+Location="$(cd $(dirname $0) && pwd)"
+source $Location/test-utils.sh
+
+Dir=/tmp/grok-$Name
+rm -rf $Dir
+mkdir -p $Dir
+echo 'waldo' > $Dir/a.go
+echo 'waldo' > $Dir/a_test.go
+echo 'waldo' > $Dir/b.web
+echo 'waldo' > $Dir/notes.txt
+printf '#!/usr/bin/env python3\nwaldo\n' > $Dir/script
+printf '#!/bin/bash\nwaldo\n' > $Dir/run
+
+runcmd $PUT -M 1 -W -a waldo --type go $Dir
+runcmd $PUT -M 1 -W -a waldo --type go --type-not test $Dir
+runcmd $PUT -M 1 -W -a waldo -t py -t sh $Dir
+runcmd $PUT -M 1 -W -a waldo -T go -T py $Dir
+runcmd $PUT -M 1 -W -a waldo --type-add 'web:*.web' --type web -i 'notes' $Dir
+runcmdst 2 2 "$PUT -W -a waldo --type-add 'web' $Dir 2>/dev/null"
+runcmdst 2 2 "$PUT -W -a waldo --type nope $Dir 2>/dev/null"
+runcmd "$PUT --type-add 'web:*.web' --type-list | grep -E '^(go|web) '"
+rm -rf $Dir
@@ -0,0 +1,29 @@
+#!/bin/bash
+#
+# Test the glob patterns: -g and --prune-glob.
+#
+
+# ================================================================
+# Includes
+# ================================================================
+Location="$(cd $(dirname $0) && pwd)"
+source $Location/test-utils.sh
+
+Dir=/tmp/grok-$Name
+rm -rf $Dir
+mkdir -p $Dir/src/lib $Dir/src/node_modules/x $Dir/dist $Dir/docs
+for f in src/app.js src/app.min.js src/lib/util.js src/lib/util.ts \
+         src/node_modules/x/index.js dist/bundle.js docs/a.md README.md ; do
+    echo 'waldo' > $Dir/$f
+done
+
+runcmd "$PUT -M 1 -W -a waldo -g '*.js' -g '!*.min.js' $Dir"
+runcmd "$PUT -M 1 -W -a waldo -g 'src/**/*.{js,ts}' --prune-glob node_modules $Dir"
+runcmd "$PUT -M 1 -W -a waldo -g '/*.md' $Dir"
+runcmd "$PUT -M 1 -W -a waldo -g 'util.[!j]*' $Dir"
+runcmd "$PUT -M 1 -W -a waldo --prune-glob '*' --prune-glob '!src' --prune-glob '!lib' $Dir"
+# The globs are relative to each root.
+export PATH=$Location/../bin:$PATH
+runcmd "(cd $Dir/src && grok -M 1 -W -a waldo -g 'lib/*' . ../dist)"
+runcmdst 2 2 "$PUT -a waldo -g 'src/[a-' $Dir"
+rm -rf $Dir
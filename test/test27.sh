@@ -0,0 +1,31 @@
+#!/bin/bash
+#
+# Test the path display controls: --path-style, --strip-prefix and
+# --match-relative.
+#
+
+# ================================================================
+# Includes
+# ================================================================
+Location="$(cd $(dirname $0) && pwd)"
+source $Location/test-utils.sh
+
+Dir=/tmp/grok-$Name
+rm -rf $Dir
+mkdir -p $Dir/a/src $Dir/b/src
+echo 'waldo' > $Dir/a/src/x.txt
+echo 'waldo' > $Dir/b/src/y.txt
+export PATH=$Location/../bin:$PATH
+
+for Style in as-given relative absolute basename ; do
+    runcmd "(cd $Dir/a && grok -M 1 -W --no-heading --path-style $Style -a waldo ./src ../b)"
+done
+runcmd "(cd $Dir/a && grok -M 1 -W --path-style absolute --strip-prefix $Dir -a waldo . ../b/src/y.txt)"
+runcmd "(cd $Dir/a && grok -M 1 -W --vimgrep --path-style relative --strip-prefix ../ -a waldo src ../b)"
+runcmd "(cd $Dir/a && grok -M 1 -W --path-style absolute --strip-prefix $Dir/a/sr -a waldo src)"
+
+# The name patterns can match the path relative to the root.
+runcmdst 1 1 "grok -M 1 -W -i '^src/' -a waldo $Dir/a $Dir/b"
+runcmd "grok -M 1 -W --match-relative -i '^src/' -e 'y\.txt$' -a waldo $Dir/a $Dir/b"
+runcmd "grok -M 1 -W --match-relative -p '^a/src$' -a waldo $Dir"
+rm -rf $Dir
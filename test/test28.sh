@@ -0,0 +1,37 @@
+#!/bin/bash
+#
+# Test that overlapping roots and files that are reachable through
+# several paths are only searched once.
+#
+
+# ================================================================
+# Includes
+# ================================================================
+Location="$(cd $(dirname $0) && pwd)"
+source $Location/test-utils.sh
+
+Dir=/tmp/grok-$Name
+rm -rf $Dir
+mkdir -p $Dir/src/sub $Dir/other $Dir/.hid
+echo 'waldo' > $Dir/src/x.txt
+echo 'waldo' > $Dir/src/sub/y.txt
+echo 'waldo' > $Dir/.hid/z.txt
+ln $Dir/src/x.txt $Dir/other/hard.txt
+ln -s ../src/sub/y.txt $Dir/other/soft.txt
+ln -s .. $Dir/src/sub/loop
+export PATH=$Location/../bin:$PATH
+
+# Nested and repeated roots.
+runcmd "(cd $Dir && grok -M 1 -W -s -a waldo . ./src src/x.txt src)"
+runcmd "(cd $Dir && grok -M 1 -W -s -a waldo src/sub/y.txt src)"
+
+# Nested roots that the walk of the outer root does not reach.
+runcmd "(cd $Dir && grok -M 1 -W -a waldo . .hid)"
+runcmd "(cd $Dir && grok -M 1 -W -p sub -a waldo src src/sub)"
+runcmd "(cd $Dir && grok -M 1 -W -m 0 -a waldo . src/x.txt)"
+runcmd "(cd $Dir && grok -M 1 -W -s -m 1 -p other -a waldo . src)"
+runcmd "(cd $Dir && grok -M 1 -W -s -m 1 -p other -a waldo src .)"
+
+# Hard links, symbolic links and a symbolic link loop.
+runcmd "(cd $Dir && grok -M 1 -W -s -a waldo src other)"
+rm -rf $Dir
@@ -0,0 +1,31 @@
+#!/bin/bash
+#
+# Test the hidden file policy and the minimum depth.
+#
+
+# ================================================================
+# Includes
+# ================================================================
+Location="$(cd $(dirname $0) && pwd)"
+source $Location/test-utils.sh
+
+Dir=/tmp/grok-$Name
+rm -rf $Dir
+mkdir -p $Dir/.hid $Dir/a/b
+for f in top.txt .dot.txt .hid/h.txt a/a.txt a/.dot.txt a/b/b.txt ; do
+    echo 'waldo' > $Dir/$f
+done
+export PATH=$Location/../bin:$PATH
+
+# Hidden entries are skipped unless they are roots or --hidden is set.
+runcmd "(cd $Dir && grok -M 1 -W -a waldo)"
+runcmd "(cd $Dir && grok -M 1 -W --hidden -a waldo)"
+runcmd "(cd $Dir && grok -M 1 -W --hidden --no-hidden -a waldo)"
+runcmd "(cd $Dir && grok -M 1 -W -a waldo .hid .dot.txt)"
+
+# The minimum depth.
+runcmd "(cd $Dir && grok -M 1 -W --min-depth 1 -a waldo)"
+runcmd "(cd $Dir && grok -M 1 -W --min-depth 2 -a waldo)"
+runcmd "(cd $Dir && grok -M 1 -W --min-depth 1 -m 1 --hidden -a waldo)"
+runcmdst 1 1 "(cd $Dir && grok -M 1 -W --min-depth 1 -a waldo top.txt)"
+rm -rf $Dir
@@ -0,0 +1,41 @@
+#!/bin/bash
+#
+# Test the prune markers, --dir-include and the --Prune AND patterns.
+#
+
+# ================================================================
+# Includes
+# ================================================================
+Location="$(cd $(dirname $0) && pwd)"
+source $Location/test-utils.sh
+
+Dir=/tmp/grok-$Name
+rm -rf $Dir
+mkdir -p $Dir/cache $Dir/fake $Dir/skip $Dir/mod/gen $Dir/tools/build $Dir/app/build $Dir/app/gen
+for d in . cache fake skip mod mod/gen tools/build app/build app/gen ; do
+    echo 'waldo' > $Dir/$d/x.txt
+done
+echo 'Signature: 8a477f597d28d172789f06886806bc55' > $Dir/cache/CACHEDIR.TAG
+echo 'not a cache directory' > $Dir/fake/CACHEDIR.TAG
+touch $Dir/skip/.grokskip
+echo 'module mod' > $Dir/mod/go.mod
+export PATH=$Location/../bin:$PATH
+
+# The default markers, a user marker and no markers.
+runcmd "(cd $Dir && grok -M 1 -W -a waldo)"
+runcmd "(cd $Dir && grok -M 1 -W --prune-marker go.mod -a waldo)"
+runcmd "(cd $Dir && grok -M 1 -W --prune-marker go.mod -a waldo mod)"
+runcmd "(cd $Dir && grok -M 1 -W --no-prune-markers -a waldo)"
+
+# Only the subtrees that match --dir-include are searched.
+runcmd "(cd $Dir && grok -M 1 -W --dir-include '/gen$' -a waldo)"
+runcmd "(cd $Dir && grok -M 1 -W --dir-include '^app$' -a waldo)"
+runcmd "(cd $Dir && grok -M 1 -W --match-relative --dir-include '^gen$' -a waldo mod app)"
+
+# All of the --Prune patterns must match.
+runcmd "(cd $Dir && grok -M 1 -W --Prune '/build$' --Prune '^tools/' -a waldo)"
+runcmd "(cd $Dir && grok -M 1 -W --Prune '/build$' --Prune '^none/' -a waldo tools)"
+
+# Invalid markers.
+runcmdst 2 2 "grok --prune-marker a/b -a waldo $Dir"
+rm -rf $Dir
@@ -0,0 +1,44 @@
+#!/bin/bash
+#
+# Test the per-directory .grok.conf files.
+#
+
+# ================================================================
+# Includes
+# ================================================================
+Location="$(cd $(dirname $0) && pwd)"
+source $Location/test-utils.sh
+
+Dir=/tmp/grok-$Name
+rm -rf $Dir
+mkdir -p $Dir/gen/pb $Dir/gen/old $Dir/testdata/big $Dir/src
+for f in top.go gen/a.go gen/a.pb.go gen/pb/b.pb.go gen/old/c.go testdata/t.txt testdata/big/t.txt src/main.go ; do
+    echo 'waldo' > $Dir/$f
+done
+printf 'waldo\0' > $Dir/testdata/data.bin
+cat > $Dir/gen/.grok.conf <<EOF
+# Skip the protobuf code and the old generator output.
+-e '\.pb\.go\$'
+--prune-glob=old
+EOF
+cat > $Dir/testdata/.grok.conf <<EOF
+-b
+--prune-glob='/big'
+--encoding=utf-8
+EOF
+export PATH=$Location/../bin:$PATH
+
+# Each conf file only applies to its own subtree.
+runcmd "(cd $Dir && grok -M 1 -W -a waldo)"
+runcmd "(cd $Dir && grok -M 1 -W -a waldo gen src)"
+
+# The settings are layered on top of the command line.
+runcmd "(cd $Dir && grok -M 1 -W -e 'main' -a waldo)"
+
+# --no-config ignores the conf files.
+runcmd "(cd $Dir && grok -M 1 -W --no-config -a waldo)"
+
+# Options that are not allowed.
+echo '-l' >> $Dir/src/.grok.conf
+runcmdst 2 2 "(cd $Dir && grok -M 1 -a waldo src)"
+rm -rf $Dir
@@ -0,0 +1,35 @@
+#!/bin/bash
+#
+# Test searching the files in a zip archive with --zip.
+#
+
+# ================================================================
+# Includes
+# ================================================================
+Location="$(cd $(dirname $0) && pwd)"
+source $Location/test-utils.sh
+
+Dir=/tmp/grok-$Name
+rm -rf $Dir
+mkdir -p $Dir/tree/src/sub $Dir/tree/.hid
+echo 'waldo' > $Dir/tree/src/a.txt
+echo 'waldo' > $Dir/tree/src/sub/b.go
+echo 'waldo' > $Dir/tree/.hid/c.txt
+printf 'waldo\0' > $Dir/tree/src/d.bin
+echo "-e '\.go\$'" > $Dir/tree/src/sub/.grok.conf
+(cd $Dir/tree && zip -q -r ../tree.zip .)
+export PATH=$Location/../bin:$PATH
+
+# The archive is searched like a directory tree.
+runcmd "grok -M 1 -W -s --zip $Dir/tree.zip -l -a waldo"
+runcmd "grok -M 1 -W --zip $Dir/tree.zip --hidden -b -a waldo"
+runcmd "grok -M 1 -W --zip $Dir/tree.zip --no-config -a waldo src/sub src/a.txt"
+runcmd "grok -M 1 -W --zip $Dir/tree.zip -g '*.txt' --path-style basename -a waldo src"
+runcmd "(cd $Dir/tree/src && grok -M 1 -W --zip ../../tree.zip --path-style absolute -a waldo /src)"
+runcmd "(cd $Dir/tree/src && grok -M 1 -W --zip ../../tree.zip --path-style relative -a waldo /src/a.txt)"
+runcmd "(cd $Dir/tree/src && grok -M 1 -W --zip ../../tree.zip --hyperlink --color=always -a waldo src/a.txt | sed -e 's@file://[^/]*/@file://HOST/@' | cat -v)"
+
+# Paths that are not in the archive and archives that are not valid.
+runcmdst 2 2 "grok --zip $Dir/tree.zip -a waldo nope"
+runcmdst 2 2 "grok --zip $Dir/tree/src/a.txt -a waldo"
+rm -rf $Dir
@@ -0,0 +1,35 @@
+#!/bin/bash
+#
+# Test the generated, minified and vendored file detection of
+# --skip-generated.
+#
+
+# ================================================================
+# Includes
+# ================================================================
+Location="$(cd $(dirname $0) && pwd)"
+source $Location/test-utils.sh
+
+Dir=/tmp/grok-$Name
+rm -rf $Dir
+mkdir -p $Dir/src/vendor/lib $Dir/web/node_modules/x
+printf '// Code generated by protoc-gen-go. DO NOT EDIT.\n\npackage pb // waldo\n' > $Dir/src/a.pb.go
+printf '# @generated by the build\nwaldo = 1\n' > $Dir/src/gen.py
+printf 'package main\n\n// The @generated marker is only a marker at the start of a comment.\nvar waldo = "// Code generated by hand"\n' > $Dir/src/main.go
+printf 'package lib // waldo\n' > $Dir/src/vendor/lib/lib.go
+printf 'var waldo=1;' > $Dir/web/app.min.js
+for i in $(seq 1 40) ; do printf 'function f%d(a,b){return a+b}' $i >> $Dir/web/app.min.js ; done
+printf '\n' >> $Dir/web/app.min.js
+printf 'var waldo = 1;\n' > $Dir/web/app.js
+printf 'var waldo = 1;\n' > $Dir/web/node_modules/x/index.js
+export PATH=$Location/../bin:$PATH
+
+runcmd "(cd $Dir && grok -M 1 -W -s -a waldo)"
+runcmd "(cd $Dir && grok -M 1 -W -s --skip-generated -a waldo)"
+
+# Only the web files.
+runcmd "(cd $Dir && grok -M 1 -W -s --skip-generated -a waldo web)"
+
+# The vendor directories are relative to the root.
+runcmd "(cd $Dir && grok -M 1 -W --skip-generated -a waldo src/vendor)"
+rm -rf $Dir
@@ -0,0 +1,37 @@
+#!/bin/bash
+#
+# Test the binary file classifier and the --binary modes.
+#
+
+# ================================================================
+# Includes
+# ================================================================
+Location="$(cd $(dirname $0) && pwd)"
+source $Location/test-utils.sh
+
+Dir=/tmp/grok-$Name
+rm -rf $Dir
+mkdir -p $Dir
+printf 'waldo without a newline' > $Dir/oneline.txt
+printf '\xef\xbb\xbfwaldo with a BOM\n' > $Dir/bom.txt
+printf 'caf\xe9 waldo in Latin-1\n' > $Dir/latin1.txt
+printf '\x1b[31mwaldo\x1b[0m in color\n' > $Dir/color.log
+printf 'ELF\x00\x01waldo\n' > $Dir/nul.bin
+printf '\x01\x02\x03\x04\x05\x06waldo\x07\x08\x0e\x0f\n' > $Dir/control.bin
+printf '\xfd\xfc\xfb\xfa\xf0\xc0waldo\xf9\xf8\n' > $Dir/invalid.bin
+touch $Dir/empty.txt
+export PATH=$Location/../bin:$PATH
+
+runcmd "(cd $Dir && grok -M 1 -W -a waldo)"
+runcmd "(cd $Dir && grok -M 1 -W --binary=match -a waldo)"
+runcmd "(cd $Dir && grok -M 1 -W --binary=match -l -a waldo)"
+runcmd "(cd $Dir && grok -M 1 -W --binary=match --no-heading -a waldo)"
+runcmd "(cd $Dir && grok -M 1 -W --binary=text -L -a waldo nul.bin control.bin)"
+runcmd "(cd $Dir && grok -M 1 -W --binary=match -b -a waldo nul.bin)"
+runcmd "(cd $Dir && grok -M 1 -W --binary=text --binary=skip -a waldo nul.bin oneline.txt)"
+
+# The binary size limits the sample.
+runcmd "(cd $Dir && grok -M 1 -W -B 3 -a waldo nul.bin)"
+runcmdst 2 2 "grok --binary=maybe -a waldo $Dir"
+runcmdst 2 2 "grok -B -1 -a waldo $Dir"
+rm -rf $Dir
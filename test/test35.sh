@@ -0,0 +1,37 @@
+#!/bin/bash
+#
+# Test the byte order mark detection and --encoding.
+#
+
+# ================================================================
+# Includes
+# ================================================================
+Location="$(cd $(dirname $0) && pwd)"
+source $Location/test-utils.sh
+
+Dir=/tmp/grok-$Name
+rm -rf $Dir
+mkdir -p $Dir
+printf '\xef\xbb\xbfwaldo in UTF-8\n' > $Dir/utf8bom.txt
+printf '\xff\xfew\x00a\x00l\x00d\x00o\x00 \x00\xe9\x00\r\x00\n\x00n\x00o\x00\n\x00' > $Dir/utf16le.txt
+printf '\xfe\xff\x00w\x00a\x00l\x00d\x00o\x00 \xd8\x3d\xde\x00\x00\n' > $Dir/utf16be.txt
+printf '\xff\xfe\x00\x00w\x00\x00\x00a\x00\x00\x00l\x00\x00\x00d\x00\x00\x00o\x00\x00\x00\n\x00\x00\x00' > $Dir/utf32le.txt
+printf 'w\x00a\x00l\x00d\x00o\x00 \x00n\x00o\x00 \x00B\x00O\x00M\x00\n\x00' > $Dir/nobom.utf16
+printf 'caf\xe9 waldo \x80 \x93quoted\x94\n' > $Dir/cp1252.txt
+export PATH=$Location/../bin:$PATH
+
+runcmd "(cd $Dir && grok -M 1 -W -l -a waldo)"
+runcmd "(cd $Dir && grok -M 1 -W -L -a 'waldo é' utf16le.txt)"
+runcmd "(cd $Dir && grok -M 1 -W -l -a '^waldo' utf8bom.txt)"
+runcmd "(cd $Dir && grok -M 1 -W -l --encoding utf-16le -a waldo nobom.utf16 utf16be.txt)"
+runcmd "(cd $Dir && grok -M 1 -W -l --encoding windows-1252 -a 'café' cp1252.txt)"
+runcmd "(cd $Dir && grok -M 1 -W -l --encoding latin-1 -a 'café' cp1252.txt)"
+runcmd "(cd $Dir && grok -M 1 -W -v --encoding utf-16le -a waldo nobom.utf16 2>&1 | grep transcoded)"
+
+# A directory conf file sets the encoding of its subtree.
+mkdir -p $Dir/win
+cp $Dir/nobom.utf16 $Dir/win/
+echo '--encoding utf-16le' > $Dir/win/.grok.conf
+runcmd "(cd $Dir && grok -M 1 -W -a 'no BOM')"
+runcmdst 2 2 "grok --encoding ebcdic -a waldo $Dir"
+rm -rf $Dir
@@ -0,0 +1,26 @@
+#!/bin/bash
+#
+# Test that the parallel walk finds the same files as a single job.
+#
+
+# ================================================================
+# Includes
+# ================================================================
+Location="$(cd $(dirname $0) && pwd)"
+source $Location/test-utils.sh
+
+Dir=/tmp/grok-$Name
+rm -rf $Dir
+for d in a b c ; do
+    for s in x y ; do
+        mkdir -p $Dir/$d/$s/deep
+        echo 'waldo' > $Dir/$d/$s/one.txt
+        echo 'waldo' > $Dir/$d/$s/deep/two.txt
+        echo 'fred' > $Dir/$d/$s/deep/three.txt
+    done
+done
+export PATH=$Location/../bin:$PATH
+
+runcmd "(cd $Dir && grok -M 8 -W --no-heading -a waldo | sort)"
+runcmd "(cd $Dir && grok -M 8 -W -s -a waldo | grep summary)"
+rm -rf $Dir